@@ -0,0 +1,111 @@
+package djolar
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Bracket syntax, an alternative to the `q` parameter enabled by
+// Parser.Brackets, eg.,
+//
+// 	?age[gte]=18&age[lte]=65&status[in]=open&status[in]=closed
+// 	=> age >= ? AND age <= ? AND status IN (?)
+//
+// Each key is a query field and an operator, the conditions are joined with
+// AND. Values are taken as they are, except `$null`, and the booleans of
+// isnull and notnull, eg., deleted[isnull]=false => NOT (deleted_at IS
+// NULL). The values of List operators use the list syntax, eg.,
+// status[in]=a,"b,c", and repeated keys add items to the list. Repeated
+// keys of other operators add conditions.
+
+// bracketFilter the values of a field[operator] key
+type bracketFilter struct {
+	key    string
+	field  string
+	op     string
+	values []string
+}
+
+// bracketFilters collect the bracket keys of the query, sorted by key so the
+// generated clause is always the same
+func (p *Parser) bracketFilters(query url.Values, ctx *parseContext) []bracketFilter {
+	if !p.Brackets {
+		return nil
+	}
+	var filters []bracketFilter
+	for key, values := range query {
+		if !strings.HasPrefix(key, ctx.names.Prefix) || len(values) == 0 {
+			continue
+		}
+		if field, op, ok := parseBracketKey(key[len(ctx.names.Prefix):]); ok {
+			filters = append(filters, bracketFilter{key: key, field: field, op: op, values: values})
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool { return filters[i].key < filters[j].key })
+	return filters
+}
+
+// parseBracketKey split a key written field[operator], or field[operator][]
+// as sent by some clients for repeated keys
+func parseBracketKey(key string) (field, op string, ok bool) {
+	key = strings.TrimSuffix(key, "[]")
+	i := strings.IndexByte(key, '[')
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return "", "", false
+	}
+	field, op = key[:i], key[i+1:len(key)-1]
+	if !isWord(field) || !isWord(op) {
+		return "", "", false
+	}
+	return field, op, true
+}
+
+// buildBrackets build the conditions of the bracket filters through the
+// atoms of the `q` parameter, so they get the same checks
+func (p *Parser) buildBrackets(filters []bracketFilter, clause *WhereClause, ctx *parseContext) (string, bool) {
+	type atom struct {
+		key   string
+		value string
+		text  string
+		err   *AtomError
+	}
+	atoms := make([]atom, 0, len(filters))
+	for _, f := range filters {
+		op, ok := p.Operator(f.op)
+		if !ok {
+			err := &AtomError{Field: f.field, Operator: f.op, Reason: ReasonUnknownOperator}
+			atoms = append(atoms, atom{key: f.key, value: f.values[0], err: err})
+			continue
+		}
+		prefix := f.field + "__" + f.op + "__"
+		if op.List {
+			value := strings.Join(f.values, ",")
+			atoms = append(atoms, atom{key: f.key, value: value, text: prefix + value})
+			continue
+		}
+		for _, value := range f.values {
+			atoms = append(atoms, atom{key: f.key, value: value, text: prefix + escapeValue(value)})
+		}
+	}
+	if exceeds(p.Limits.MaxConditions, len(atoms)) {
+		ctx.reject(filters[0].key, 0, &AtomError{Atom: filters[0].key, Reason: ReasonLimitExceeded})
+		return "", false
+	}
+
+	where := make([]string, 0, len(atoms))
+	for _, a := range atoms {
+		var wh string
+		err := a.err
+		if err == nil {
+			wh, err = p.buildWhereClause(a.text, p.resolveField, clause, ctx)
+		}
+		if err != nil {
+			err.Atom = a.value
+			ctx.reject(a.key, 0, err)
+			continue
+		}
+		where = append(where, wh)
+	}
+	return strings.Join(where, " AND "), len(where) > 0
+}
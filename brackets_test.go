@@ -0,0 +1,103 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestBrackets(t *testing.T) {
+	p := NewParser()
+	p.Brackets = true
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+		"n": "name",
+		"s": "status",
+		"d": "deleted_at",
+	}
+	p.Metadata.FieldTypes = map[string]FieldType{"a": TypeInt}
+
+	cases := []struct {
+		query string
+		where string
+		args  []interface{}
+	}{
+		{"a[gte]=18&a[lte]=65", "age >= ? AND age <= ?", []interface{}{int64(18), int64(65)}},
+		{"s[in]=a&s[in]=b", "status IN (?)", []interface{}{[]string{"a", "b"}}},
+		{`s[in][]=a&s[in][]="b,c"`, "status IN (?)", []interface{}{[]string{"a", "b,c"}}},
+		{"s[in]=a,b&n[co]=x", "name LIKE ? ESCAPE '!' AND status IN (?)", []interface{}{"%x%", []string{"a", "b"}}},
+		{`n[eq]="a|b"\`, "name = ?", []interface{}{`"a|b"\`}},
+		{"n[eq]=$null&d[isnull]=", "deleted_at IS NULL AND name IS NULL", []interface{}{}},
+		{"d[isnull]=false&n[notnull]=true", "NOT (deleted_at IS NULL) AND name IS NOT NULL", []interface{}{}},
+		{"d[isnull]=maybe&n[eq]=x", "name = ?", []interface{}{"x"}},
+		{"n[eq]=x&n[eq]=y", "name = ? AND name = ?", []interface{}{"x", "y"}},
+		{"q=s__eq__a||s__eq__b&a[gt]=1", "(status = ? OR status = ?) AND age > ?", []interface{}{"a", "b", int64(1)}},
+		{"a=1&a[=1&[eq]=1", "", []interface{}{}},
+	}
+	for _, c := range cases {
+		qv, err := url.ParseQuery(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := p.Parse(qv)
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("exp: %v, got: %v", c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("exp: %v, got: %v", c.args, res.WhereClause.Arguments)
+		}
+	}
+
+	// same result as the q parameter
+	exp, _ := p.ParseQuery("q=a__gte__18|s__in__[a,b]&s=-a")
+	res, _ := p.ParseQuery("a[gte]=18&s[in]=a&s[in]=b&s=-a")
+	if !reflect.DeepEqual(res, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, res)
+	}
+
+	// disabled by default
+	p.Brackets = false
+	if res, _ := p.ParseQuery("a[gte]=18"); res.WhereClause.Where != "" {
+		t.Fatalf("exp: empty where, got: %v", res.WhereClause.Where)
+	}
+}
+
+func TestBracketsErrors(t *testing.T) {
+	p := NewParser()
+	p.Brackets = true
+	p.Params.Prefix = "users."
+	p.Metadata.QueryMapping = map[string]string{"a": "age"}
+	p.Metadata.FieldTypes = map[string]FieldType{"a": TypeInt}
+	p.Metadata.DefaultSearch = map[string]interface{}{"age > ?": 1}
+
+	qv, _ := url.ParseQuery("users.a[gt]=x&users.a[zz]=1&users.b[eq]=1&a[gt]=1")
+	res, err := p.ParseStrict(qv)
+	if res != nil {
+		t.Fatalf("exp: nil result, got: %v", res)
+	}
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("exp: *ParseError, got: %v", err)
+	}
+	exp := []*AtomError{
+		{Param: "users.a[gt]", Atom: "x", Field: "a", Operator: "gt", Reason: ReasonInvalidValue},
+		{Param: "users.a[zz]", Atom: "1", Field: "a", Operator: "zz", Reason: ReasonUnknownOperator},
+		{Param: "users.b[eq]", Atom: "1", Field: "b", Operator: "eq", Reason: ReasonUnknownField},
+	}
+	if !reflect.DeepEqual(perr.Errors, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, perr.Errors)
+	}
+
+	// filters replace the default search
+	res = p.Parse(qv)
+	if res.WhereClause.Where != "" {
+		t.Fatalf("exp: empty where, got: %v", res.WhereClause.Where)
+	}
+
+	p.Limits.MaxConditions = 1
+	qv, _ = url.ParseQuery("users.a[gt]=1&users.a[lt]=9")
+	if _, err := p.ParseStrict(qv); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("exp: limit exceeded, got: %v", err)
+	}
+}
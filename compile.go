@@ -0,0 +1,279 @@
+package djolar
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// CompiledParser immutable parser built by Parser.Compile. It holds its own
+// copy of the configuration, so it is safe for concurrent use, eg., shared
+// by every HTTP handler of a resource:
+//
+//	var users = djolar.NewParser().MustCompile()
+//
+//	func listUsers(w http.ResponseWriter, r *http.Request) {
+//		res, err := users.ParseQuery(r.URL.RawQuery)
+//		...
+//	}
+type CompiledParser struct {
+	p Parser
+}
+
+// Compile validate the configuration of the parser, and build an immutable
+// copy of it. Later changes to the parser, its meta data or its operators
+// do not affect the compiled parser.
+func (p *Parser) Compile() (*CompiledParser, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	c := &CompiledParser{p: *p}
+	c.p.Metadata = p.Metadata.clone()
+	c.p.operators = cloneOperators(p.operators)
+	if c.p.operators == nil {
+		c.p.operators = DefaultOperators()
+	}
+	if c.p.GetPlaceHolder == nil {
+		c.p.GetPlaceHolder = defaultPlaceHolderFunc
+	}
+	if c.p.GetArgMapKey == nil {
+		c.p.GetArgMapKey = defaultArgMapFunc
+	}
+	c.p.columns, c.p.aggregates = c.p.resolveColumns()
+	params := p.Params.resolve()
+	c.p.params = &params
+	return c, nil
+}
+
+// MustCompile is like Compile but panics if the configuration is invalid
+func (p *Parser) MustCompile() *CompiledParser {
+	c, err := p.Compile()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Parse see Parser.Parse
+func (c *CompiledParser) Parse(query url.Values) *ParseResult {
+	return c.p.Parse(query)
+}
+
+// ParseStrict see Parser.ParseStrict
+func (c *CompiledParser) ParseStrict(query url.Values) (*ParseResult, error) {
+	return c.p.ParseStrict(query)
+}
+
+// ParseQuery see Parser.ParseQuery
+func (c *CompiledParser) ParseQuery(query string) (*ParseResult, error) {
+	return c.p.ParseQuery(query)
+}
+
+// ParseURI see Parser.ParseURI
+func (c *CompiledParser) ParseURI(uri string) (*ParseResult, error) {
+	return c.p.ParseURI(uri)
+}
+
+// ParseJSON see Parser.ParseJSON
+func (c *CompiledParser) ParseJSON(data []byte) (*ParseResult, error) {
+	return c.p.ParseJSON(data)
+}
+
+// ParseDocument see Parser.ParseDocument
+func (c *CompiledParser) ParseDocument(doc *FilterDocument) (*ParseResult, error) {
+	return c.p.ParseDocument(doc)
+}
+
+// Metadata copy of the meta data of the parser
+func (c *CompiledParser) Metadata() MetaData {
+	return c.p.Metadata.clone()
+}
+
+// Validate check the configuration of the parser: query fields must be
+// words mapped to a column, the field lists and rules must refer to mapped
+// fields, operators, field types and aggregate functions must exist, and
+// parameter names must be distinct.
+func (p *Parser) Validate() error {
+	md := &p.Metadata
+	for field, column := range md.QueryMapping {
+		if !isWord(field) {
+			return fmt.Errorf("djolar: invalid query field %q", field)
+		}
+		if column == "" {
+			return fmt.Errorf("djolar: query field %q has no column", field)
+		}
+	}
+	lists := []struct {
+		name   string
+		fields []string
+	}{
+		{"SortableFields", md.SortableFields},
+		{"GroupableFields", md.GroupableFields},
+		{"AggregatableFields", md.AggregatableFields},
+	}
+	for _, list := range lists {
+		for _, field := range list.fields {
+			if _, ok := md.QueryMapping[field]; !ok {
+				return fmt.Errorf("djolar: %s: unknown query field %q", list.name, field)
+			}
+		}
+	}
+	for field, t := range md.FieldTypes {
+		if _, ok := md.QueryMapping[field]; !ok {
+			return fmt.Errorf("djolar: FieldTypes: unknown query field %q", field)
+		}
+		if !t.valid() {
+			return fmt.Errorf("djolar: FieldTypes: unknown type %q of query field %q", t, field)
+		}
+	}
+	for name, fn := range md.AggregateFunctions {
+		if !isWord(name) || !isIdentifier(fn) {
+			return fmt.Errorf("djolar: invalid aggregate function %q: %q", name, fn)
+		}
+	}
+	for field, ops := range md.AllowedOperators {
+		if _, ok := md.QueryMapping[field]; !ok && !p.isAggregate(field) {
+			return fmt.Errorf("djolar: AllowedOperators: unknown query field %q", field)
+		}
+		if err := p.checkOperators(ops); err != nil {
+			return err
+		}
+	}
+	if err := p.checkOperators(md.DefaultAllowedOperators); err != nil {
+		return err
+	}
+	if err := p.Params.validate(); err != nil {
+		return err
+	}
+	if p.Syntax != SyntaxDjolar && p.Syntax != SyntaxRSQL {
+		return fmt.Errorf("djolar: unknown syntax %d", p.Syntax)
+	}
+	if md.DefaultLimit < 0 || md.MaxLimit < 0 || md.MaxOffset < 0 {
+		return fmt.Errorf("djolar: negative pagination limit")
+	}
+	if md.MaxLimit > 0 && md.DefaultLimit > md.MaxLimit {
+		return fmt.Errorf("djolar: DefaultLimit %d is over MaxLimit %d", md.DefaultLimit, md.MaxLimit)
+	}
+	return nil
+}
+
+func (p *Parser) checkOperators(ops []string) error {
+	for _, op := range ops {
+		if _, ok := p.Operator(op); !ok {
+			return fmt.Errorf("djolar: unknown operator %q", op)
+		}
+	}
+	return nil
+}
+
+// resolveColumns resolve every query field, and every aggregate allowed in
+// the SELECT and HAVING clauses, so that they are looked up once per request
+func (p *Parser) resolveColumns() (map[string]string, map[string]string) {
+	columns := make(map[string]string, len(p.Metadata.QueryMapping))
+	aggregates := make(map[string]string)
+	for field := range p.Metadata.QueryMapping {
+		columns[field], _ = p.resolveField(field)
+		for key := range p.aggregateFunctions() {
+			alias := field + "__" + key
+			if aggregate, reason := p.resolveAggregate(alias); reason == "" {
+				aggregates[alias] = aggregate
+			}
+		}
+	}
+	return columns, aggregates
+}
+
+// isAggregate check if the field is an aggregate alias of a mapped field,
+// eg., a__sum
+func (p *Parser) isAggregate(field string) bool {
+	_, reason := p.resolveAggregate(field)
+	return reason == ""
+}
+
+// clone deep copy of the meta data, nil maps and lists stay nil
+func (md MetaData) clone() MetaData {
+	md.QueryMapping = cloneStrings(md.QueryMapping)
+	md.DefaultSearch = cloneSearch(md.DefaultSearch)
+	md.ForceSearch = cloneSearch(md.ForceSearch)
+	md.AggregateFunctions = cloneStrings(md.AggregateFunctions)
+	if md.FieldTypes != nil {
+		types := make(map[string]FieldType, len(md.FieldTypes))
+		for field, t := range md.FieldTypes {
+			types[field] = t
+		}
+		md.FieldTypes = types
+	}
+	if md.AllowedOperators != nil {
+		allowed := make(map[string][]string, len(md.AllowedOperators))
+		for field, ops := range md.AllowedOperators {
+			allowed[field] = cloneList(ops)
+		}
+		md.AllowedOperators = allowed
+	}
+	md.DefaultConditions = cloneConditions(md.DefaultConditions)
+	md.ForceConditions = cloneConditions(md.ForceConditions)
+	md.DefaultOrderBy = cloneList(md.DefaultOrderBy)
+	md.ForceOrderBy = cloneList(md.ForceOrderBy)
+	md.DefaultAllowedOperators = cloneList(md.DefaultAllowedOperators)
+	md.SortableFields = cloneList(md.SortableFields)
+	md.GroupableFields = cloneList(md.GroupableFields)
+	md.AggregatableFields = cloneList(md.AggregatableFields)
+	return md
+}
+
+func cloneStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func cloneSearch(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func cloneOperators(m map[string]Operator) map[string]Operator {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]Operator, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func cloneList(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
+
+func cloneArgs(s []interface{}) []interface{} {
+	if s == nil {
+		return nil
+	}
+	return append(make([]interface{}, 0, len(s)), s...)
+}
+
+func cloneConditions(conds []Condition) []Condition {
+	if conds == nil {
+		return nil
+	}
+	c := make([]Condition, len(conds))
+	for i, cond := range conds {
+		c[i] = Condition{Where: cond.Where, Args: cloneArgs(cond.Args)}
+	}
+	return c
+}
@@ -0,0 +1,134 @@
+package djolar
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCompileValidate(t *testing.T) {
+	cases := []struct {
+		md  MetaData
+		err string
+	}{
+		{MetaData{QueryMapping: map[string]string{"a-b": "age"}}, `invalid query field "a-b"`},
+		{MetaData{QueryMapping: map[string]string{"a": ""}}, `query field "a" has no column`},
+		{MetaData{QueryMapping: map[string]string{"a": "age"}, SortableFields: []string{"b"}}, `SortableFields: unknown query field "b"`},
+		{MetaData{QueryMapping: map[string]string{"a": "age"}, FieldTypes: map[string]FieldType{"a": "date"}}, `unknown type "date"`},
+		{MetaData{QueryMapping: map[string]string{"a": "age"}, AllowedOperators: map[string][]string{"a": {"eq", "zz"}}}, `unknown operator "zz"`},
+		{MetaData{QueryMapping: map[string]string{"a": "age"}, AllowedOperators: map[string][]string{"b": {"eq"}}}, `AllowedOperators: unknown query field "b"`},
+		{MetaData{QueryMapping: map[string]string{"a": "age"}, DefaultAllowedOperators: []string{"xx"}}, `unknown operator "xx"`},
+		{MetaData{AggregateFunctions: map[string]string{"sum": "SUM(1)"}}, `invalid aggregate function "sum"`},
+		{MetaData{DefaultLimit: 50, MaxLimit: 20}, "DefaultLimit 50 is over MaxLimit 20"},
+	}
+	for _, c := range cases {
+		p := NewParser()
+		p.Metadata = c.md
+		_, err := p.Compile()
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("exp: %v, got: %v", c.err, err)
+		}
+	}
+
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{"a": "age"}
+	p.Metadata.AllowedOperators = map[string][]string{"a__sum": {"gt"}}
+	if _, err := p.Compile(); err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+}
+
+func TestCompileIsolated(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{"a": "age"}
+	p.Metadata.SortableFields = []string{"a"}
+	p.Metadata.GroupableFields = nil
+	c := p.MustCompile()
+
+	p.Metadata.QueryMapping["a"] = "other"
+	p.Metadata.SortableFields[0] = "b"
+	p.RemoveOperator("eq")
+
+	res, _ := c.ParseQuery("q=a__eq__1&s=-a")
+	if res.WhereClause.Where != "age = ?" {
+		t.Fatalf("exp: %v, got: %v", "age = ?", res.WhereClause.Where)
+	}
+	if res.OrderByClause != "age DESC" {
+		t.Fatalf("exp: %v, got: %v", "age DESC", res.OrderByClause)
+	}
+	if md := c.Metadata(); md.SortableFields[0] != "a" || md.GroupableFields != nil {
+		t.Fatalf("exp: nil lists kept, got: %v", md)
+	}
+}
+
+func TestMustCompilePanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("exp: panic for invalid meta data")
+		}
+	}()
+	p := NewParser()
+	p.Metadata.SortableFields = []string{"x"}
+	p.MustCompile()
+}
+
+func TestCompiledParserConcurrent(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"n":  "name",
+		"a":  "age",
+		"id": "id",
+	}
+	p.Metadata.FieldTypes = map[string]FieldType{"a": TypeInt, "id": TypeInt}
+	p.Metadata.CursorTiebreaker = "id"
+	p.Metadata.DefaultLimit = 20
+	p.Dialect = Postgres
+	p.Limits = DefaultLimits
+	c := p.MustCompile()
+
+	next, err := (&ParseResult{CursorColumns: []string{"age", "id"}}).NextCursor(30, 7)
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	queries := []string{
+		"q=n__eq__bob|a__in__[1,2,3]",
+		"q=(a__gte__18||n__co__x)|!n__isnull__&s=-a",
+		"q=a__bt__[1,9]&f=n,a__sum&g=n&h=a__sum__gt__10",
+		"s=a&cursor=" + next,
+	}
+	exp := make([]*ParseResult, len(queries))
+	for i, q := range queries {
+		exp[i], _ = c.ParseQuery(q)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				n := (g + i) % len(queries)
+				res, err := c.ParseQuery(queries[n])
+				if err != nil || !reflect.DeepEqual(res, exp[n]) {
+					errs <- fmt.Errorf("exp: %v, got: %v (%v)", exp[n], res, err)
+					return
+				}
+				if _, err := c.ParseURI("http://abc.com?" + queries[n]); err != nil {
+					errs <- err
+					return
+				}
+				qv, _ := url.ParseQuery(queries[n])
+				c.Parse(qv)
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
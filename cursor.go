@@ -0,0 +1,226 @@
+package djolar
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Keyset (cursor) pagination.
+//
+// When MetaData.CursorTiebreaker is set, the unique tiebreaker column is
+// appended to the ORDER BY clause, and ParseResult.CursorColumns lists the
+// columns the cursor is made of. The cursor of the next page is built from
+// the values of the last row with ParseResult.NextCursor, and sent back in
+// the `cursor` parameter, which is turned into a tuple comparison:
+//
+// 	s=-a&cursor=...
+// 	=> ORDER BY age DESC,id ASC
+// 	=> WHERE (age < ? OR age = ? AND id > ?)
+
+// sortKey column of the ORDER BY clause
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// parseSortKey parse an ORDER BY item, eg., "age DESC"
+func parseSortKey(order string) sortKey {
+	order = strings.TrimSpace(order)
+	if i := strings.LastIndexByte(order, ' '); i > 0 {
+		switch strings.ToUpper(order[i+1:]) {
+		case "DESC":
+			return sortKey{column: unquoteColumn(strings.TrimSpace(order[:i])), desc: true}
+		case "ASC":
+			return sortKey{column: unquoteColumn(strings.TrimSpace(order[:i]))}
+		}
+	}
+	return sortKey{column: unquoteColumn(order)}
+}
+
+// keysetOrder append the tiebreaker to the order by items if missing, and
+// return the sort keys of the cursor
+func (p *Parser) keysetOrder(orderby []string) ([]string, []sortKey) {
+	keys := make([]sortKey, 0, len(orderby)+1)
+	for _, order := range orderby {
+		key := parseSortKey(order)
+		keys = append(keys, key)
+		if key.column == p.Metadata.CursorTiebreaker {
+			return orderby[:len(keys)], keys
+		}
+	}
+	orderby = append(orderby, p.quoteColumn(p.Metadata.CursorTiebreaker)+" ASC")
+	keys = append(keys, sortKey{column: p.Metadata.CursorTiebreaker})
+	return orderby, keys
+}
+
+// buildCursor decode the cursor, and build the condition selecting the rows
+// after it, appending the arguments to the clause
+func (p *Parser) buildCursor(cursor string, keys []sortKey, clause *WhereClause, ctx *parseContext) (string, bool) {
+	if exceeds(p.Limits.MaxValueLength, len(cursor)) {
+		ctx.reject(ctx.names.Cursor, 0, &AtomError{Atom: cursor, Field: ctx.names.Cursor, Reason: ReasonLimitExceeded})
+		return "", false
+	}
+	values, err := p.decodeCursor(cursor, keys, ctx.now)
+	if err != nil {
+		ctx.reject(ctx.names.Cursor, 0, &AtomError{Atom: cursor, Field: ctx.names.Cursor, Reason: ReasonInvalidValue})
+		return "", false
+	}
+
+	columns := make([]string, len(keys))
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = p.quoteColumn(key.column)
+		var argKey string
+		names[i], argKey = p.argName(key.column, ctx)
+		clause.ArgumentMap[argKey] = values[i]
+	}
+
+	uniform := true
+	for _, key := range keys {
+		uniform = uniform && key.desc == keys[0].desc
+	}
+	if uniform && (len(keys) == 1 || rowValues(p.Dialect)) {
+		cmp := ">"
+		if keys[0].desc {
+			cmp = "<"
+		}
+		placeholders := make([]string, len(keys))
+		for i := range keys {
+			placeholders[i] = p.placeholder(names[i], ctx)
+		}
+		clause.Arguments = append(clause.Arguments, values...)
+		if len(keys) == 1 {
+			return fmt.Sprintf("%s %s %s", columns[0], cmp, placeholders[0]), true
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), cmp, strings.Join(placeholders, ", ")), true
+	}
+
+	// mixed directions, or no row values, expand the comparison
+	// (a, b) after (x, y) => a > x OR a = x AND b < y
+	terms := make([]string, 0, len(keys))
+	for i, key := range keys {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", columns[j], p.placeholder(names[j], ctx)))
+			clause.Arguments = append(clause.Arguments, values[j])
+		}
+		cmp := ">"
+		if key.desc {
+			cmp = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", columns[i], cmp, p.placeholder(names[i], ctx)))
+		clause.Arguments = append(clause.Arguments, values[i])
+		terms = append(terms, strings.Join(parts, " AND "))
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", true
+}
+
+func (p *Parser) decodeCursor(cursor string, keys []sortKey, now time.Time) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var raw []interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if len(raw) != len(keys) {
+		return nil, errors.New("djolar: cursor does not match the order")
+	}
+
+	values := make([]interface{}, len(raw))
+	for i, value := range raw {
+		t, typed := p.columnType(keys[i].column)
+		switch v := value.(type) {
+		case json.Number:
+			if typed {
+				value, err = convertValue(t, v.String(), p.Metadata.TimeLocation, now)
+			} else if n, ierr := v.Int64(); ierr == nil {
+				value = n
+			} else {
+				value, err = v.Float64()
+			}
+		case string:
+			if typed {
+				value, err = convertValue(t, v, p.Metadata.TimeLocation, now)
+			}
+		case bool:
+		default:
+			err = fmt.Errorf("djolar: unsupported cursor value %v", v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// columnType type of the query fields mapped to the column
+func (p *Parser) columnType(column string) (FieldType, bool) {
+	fields := make([]string, 0, 1)
+	for field, col := range p.Metadata.QueryMapping {
+		if col == column {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if t, ok := p.Metadata.FieldTypes[field]; ok {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// NextCursor build the cursor of the page following the row with the given
+// values, one value per column of CursorColumns. Rows after a NULL cannot be
+// selected with comparisons, so NULL values are rejected, the cursor columns
+// must be NOT NULL.
+func (r *ParseResult) NextCursor(values ...interface{}) (string, error) {
+	if len(r.CursorColumns) == 0 {
+		return "", errors.New("djolar: keyset pagination is not enabled, see MetaData.CursorTiebreaker")
+	}
+	if len(values) != len(r.CursorColumns) {
+		return "", fmt.Errorf("djolar: cursor needs %d values, got %d", len(r.CursorColumns), len(values))
+	}
+
+	normalized := make([]interface{}, len(values))
+	for i, value := range values {
+		if valuer, ok := value.(driver.Valuer); ok && !isNil(value) {
+			v, err := valuer.Value()
+			if err != nil {
+				return "", err
+			}
+			value = v
+		}
+		if isNil(value) {
+			return "", fmt.Errorf("djolar: cursor column %q is NULL, keyset pagination needs NOT NULL columns", r.CursorColumns[i])
+		}
+		normalized[i] = value
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// isNil check if the value is nil, or a nil pointer
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
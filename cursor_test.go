@@ -0,0 +1,191 @@
+package djolar
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCursorOrder(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"a": "age", "n": "name", "id": "id"},
+		FieldTypes:       map[string]FieldType{"a": TypeInt, "id": TypeInt},
+		CursorTiebreaker: "id",
+	}
+
+	cases := []struct {
+		query   string
+		orderby string
+		columns []string
+	}{
+		{"", "id ASC", []string{"id"}},
+		{"s=-a", "age DESC,id ASC", []string{"age", "id"}},
+		{"s=-id,a", "id DESC", []string{"id"}},
+	}
+	for _, c := range cases {
+		res, _ := p.ParseQuery(c.query)
+		if res.OrderByClause != c.orderby {
+			t.Fatalf("exp: %v, got: %v", c.orderby, res.OrderByClause)
+		}
+		if !reflect.DeepEqual(res.CursorColumns, c.columns) {
+			t.Fatalf("exp: %v, got: %v", c.columns, res.CursorColumns)
+		}
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"a": "age", "n": "name", "id": "id"},
+		FieldTypes:       map[string]FieldType{"a": TypeInt, "id": TypeInt},
+		CursorTiebreaker: "id",
+	}
+
+	cases := []struct {
+		order  string
+		values []interface{}
+		where  string
+		args   []interface{}
+	}{
+		{"", []interface{}{10}, "id > ?", []interface{}{int64(10)}},
+		{"a", []interface{}{18, 10}, "(age, id) > (?, ?)", []interface{}{int64(18), int64(10)}},
+		{"-a,-id", []interface{}{18, 10}, "(age, id) < (?, ?)", []interface{}{int64(18), int64(10)}},
+		{"-a", []interface{}{18, 10}, "(age < ? OR age = ? AND id > ?)", []interface{}{int64(18), int64(18), int64(10)}},
+		{"n,-a", []interface{}{"bob", 18, 10},
+			"(name > ? OR name = ? AND age < ? OR name = ? AND age = ? AND id > ?)",
+			[]interface{}{"bob", "bob", int64(18), "bob", int64(18), int64(10)}},
+	}
+	for _, c := range cases {
+		res := p.Parse(url.Values{"s": {c.order}})
+		cursor, err := res.NextCursor(c.values...)
+		if err != nil {
+			t.Fatalf("exp: no err, got: %v", err)
+		}
+
+		res = p.Parse(url.Values{"s": {c.order}, "cursor": {cursor}, "offset": {"20"}})
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("exp: %v, got: %v", c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("exp: %v, got: %v", c.args, res.WhereClause.Arguments)
+		}
+		if res.Offset != 0 {
+			t.Fatalf("exp: %v, got: %v", 0, res.Offset)
+		}
+	}
+}
+
+func TestParseCursorWithSearch(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"a": "age", "n": "name", "id": "id"},
+		FieldTypes:       map[string]FieldType{"a": TypeInt, "id": TypeInt},
+		CursorTiebreaker: "id",
+	}
+	res, _ := p.ParseQuery("q=n__eq__a||n__eq__b&s=-a")
+	cursor, _ := res.NextCursor(18, 10)
+
+	res = p.Parse(url.Values{"q": {"n__eq__a||n__eq__b"}, "s": {"-a"}, "cursor": {cursor}})
+	exp := "(name = ? OR name = ?) AND (age < ? OR age = ? AND id > ?)"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"a", "b", int64(18), int64(18), int64(10)}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"a": "age", "n": "name", "id": "id"},
+		FieldTypes:       map[string]FieldType{"a": TypeInt, "id": TypeInt},
+		CursorTiebreaker: "id",
+	}
+	res, _ := p.ParseQuery("s=a")
+
+	if _, err := res.NextCursor(1); err == nil {
+		t.Fatalf("exp: err for missing value, got: nil")
+	}
+
+	uuid, _ := ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	cursor, err := res.NextCursor(18, uuid)
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	values, err := p.decodeCursor(cursor, []sortKey{{column: "age"}, {column: "uuid"}}, time.Now())
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	exp := []interface{}{int64(18), "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}
+	if !reflect.DeepEqual(values, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, values)
+	}
+
+	// NULL values cannot be compared
+	var deleted *time.Time
+	for _, value := range []interface{}{nil, deleted, sql.NullTime{}} {
+		if _, err := res.NextCursor(value, 10); err == nil {
+			t.Fatalf("exp: err for NULL value %#v, got: nil", value)
+		}
+	}
+
+	p.Metadata.CursorTiebreaker = ""
+	res, _ = p.ParseQuery("s=a")
+	if _, err := res.NextCursor(18); err == nil {
+		t.Fatalf("exp: err when keyset pagination is disabled, got: nil")
+	}
+}
+
+func TestParseNullableCursor(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"d": "deleted_at", "id": "id"},
+		FieldTypes:       map[string]FieldType{"d": TypeTime, "id": TypeInt},
+		CursorTiebreaker: "id",
+	}
+
+	// valid nullable values round trip
+	deleted := time.Date(2021, 1, 11, 8, 30, 0, 0, time.UTC)
+	for _, value := range []interface{}{deleted, &deleted, sql.NullTime{Time: deleted, Valid: true}} {
+		res, _ := p.ParseQuery("s=d")
+		cursor, err := res.NextCursor(value, 10)
+		if err != nil {
+			t.Fatalf("exp: no err, got: %v", err)
+		}
+		res, err = p.ParseStrict(url.Values{"s": {"d"}, "cursor": {cursor}})
+		if err != nil {
+			t.Fatalf("exp: no err, got: %v", err)
+		}
+		args := []interface{}{deleted, int64(10)}
+		if res.WhereClause.Where != "(deleted_at, id) > (?, ?)" || !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+			t.Fatalf("exp: %v, got: %v %v", args, res.WhereClause.Where, res.WhereClause.Arguments)
+		}
+	}
+}
+
+func TestParseInvalidCursor(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"a": "age", "n": "name", "id": "id"},
+		FieldTypes:       map[string]FieldType{"a": TypeInt, "id": TypeInt},
+		CursorTiebreaker: "id",
+	}
+
+	for _, cursor := range []string{"!!", "e30", "WzFd", "WyJ4IiwxXQ"} {
+		query := url.Values{"s": {"a"}, "cursor": {cursor}}
+		_, err := p.ParseStrict(query)
+		var perr *ParseError
+		if !errors.As(err, &perr) || len(perr.Errors) != 1 || perr.Errors[0].Reason != ReasonInvalidValue {
+			t.Fatalf("%s: exp: invalid value, got: %v", cursor, err)
+		}
+		if res := p.Parse(query); res.WhereClause.Where != "" {
+			t.Fatalf("exp: %v, got: %v", "", res.WhereClause.Where)
+		}
+	}
+}
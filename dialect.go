@@ -0,0 +1,239 @@
+package djolar
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Dialect SQL flavour of the generated clauses. When the parser has a
+// dialect, placeholders are numbered across the WHERE and HAVING clauses,
+// list arguments (eg., in, ni) are expanded into one placeholder per item,
+// and mapped columns are quoted.
+type Dialect interface {
+	// Placeholder placeholder of the n-th argument of the query, from 1
+	Placeholder(n int) string
+
+	// Quote quote an identifier, eg., name => "name"
+	Quote(identifier string) string
+
+	// LimitClause clause selecting the page window of a statement, no limit
+	// if limit is 0, ordered tells if the statement has an ORDER BY clause
+	LimitClause(limit, offset int, ordered bool) string
+}
+
+// RowValueDialect optional interface of the dialects telling whether row
+// value comparisons, eg., (a, b) > (?, ?), are supported. Dialects which do
+// not implement it are assumed to support them.
+type RowValueDialect interface {
+	RowValues() bool
+}
+
+// builtin dialects
+var (
+	// Postgres $1 placeholders, "name" identifiers
+	Postgres Dialect = sqlDialect{placeholder: "$", numbered: true, open: `"`, close: `"`}
+
+	// MySQL ? placeholders, `name` identifiers
+	MySQL Dialect = sqlDialect{placeholder: "?", open: "`", close: "`", noLimit: "18446744073709551615"}
+
+	// SQLite ? placeholders, "name" identifiers
+	SQLite Dialect = sqlDialect{placeholder: "?", open: `"`, close: `"`, noLimit: "-1"}
+
+	// SQLServer @p1 placeholders, [name] identifiers
+	SQLServer Dialect = sqlDialect{placeholder: "@p", numbered: true, open: "[", close: "]", fetch: true, noRowValues: true}
+)
+
+type sqlDialect struct {
+	placeholder string
+	numbered    bool
+	open, close string
+
+	// limit used for an offset without limit, if OFFSET needs a LIMIT
+	noLimit string
+
+	// OFFSET ... FETCH syntax instead of LIMIT ... OFFSET
+	fetch bool
+
+	// no row value comparisons
+	noRowValues bool
+}
+
+func (d sqlDialect) Placeholder(n int) string {
+	if d.numbered {
+		return d.placeholder + strconv.Itoa(n)
+	}
+	return d.placeholder
+}
+
+func (d sqlDialect) Quote(identifier string) string {
+	return d.open + strings.Replace(identifier, d.close, d.close+d.close, -1) + d.close
+}
+
+func (d sqlDialect) RowValues() bool {
+	return !d.noRowValues
+}
+
+func (d sqlDialect) LimitClause(limit, offset int, ordered bool) string {
+	if d.fetch {
+		return fetchClause(limit, offset, ordered)
+	}
+	return limitClause(limit, offset, d.noLimit)
+}
+
+// limitClause LIMIT ... OFFSET ... clause
+func limitClause(limit, offset int, noLimit string) string {
+	clause := make([]string, 0, 2)
+	if limit > 0 {
+		clause = append(clause, "LIMIT "+strconv.Itoa(limit))
+	} else if offset > 0 && noLimit != "" {
+		clause = append(clause, "LIMIT "+noLimit)
+	}
+	if offset > 0 {
+		clause = append(clause, "OFFSET "+strconv.Itoa(offset))
+	}
+	return strings.Join(clause, " ")
+}
+
+// fetchClause OFFSET ... FETCH clause, which is only valid after ORDER BY
+func fetchClause(limit, offset int, ordered bool) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+	clause := "OFFSET " + strconv.Itoa(offset) + " ROWS"
+	if limit > 0 {
+		clause += " FETCH NEXT " + strconv.Itoa(limit) + " ROWS ONLY"
+	}
+	if !ordered {
+		clause = "ORDER BY (SELECT NULL) " + clause
+	}
+	return clause
+}
+
+// rowValues check if the dialect supports row value comparisons
+func rowValues(d Dialect) bool {
+	if rd, ok := d.(RowValueDialect); ok {
+		return rd.RowValues()
+	}
+	return true
+}
+
+// placeholder placeholder of the next argument bound to the query field
+func (p *Parser) placeholder(field string, ctx *parseContext) string {
+	if p.Dialect == nil {
+		if p.GetPlaceHolder == nil {
+			return defaultPlaceHolderFunc(&p.Metadata, field)
+		}
+		return p.GetPlaceHolder(&p.Metadata, field)
+	}
+	ctx.argc++
+	return p.Dialect.Placeholder(ctx.argc)
+}
+
+// bindArgument placeholder of an argument, and args with the arguments to
+// bind appended. With a dialect, lists get one placeholder per item, eg.,
+// "$1, $2".
+func (p *Parser) bindArgument(field string, arg interface{}, args []interface{}, ctx *parseContext) (string, []interface{}) {
+	if p.Dialect == nil || !isList(arg) {
+		return p.placeholder(field, ctx), append(args, arg)
+	}
+	v := reflect.ValueOf(arg)
+	var b strings.Builder
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(p.placeholder(field, ctx))
+		args = append(args, v.Index(i).Interface())
+	}
+	return b.String(), args
+}
+
+// bindCondition rewrite the `?` of a raw condition with the placeholders
+// of the dialect, quoted strings are left untouched
+func (p *Parser) bindCondition(cond Condition, ctx *parseContext) (string, []interface{}) {
+	if p.Dialect == nil {
+		return cond.Where, cond.Args
+	}
+	var b strings.Builder
+	args := make([]interface{}, 0, len(cond.Args))
+	n := 0
+	quote := byte(0)
+	for i := 0; i < len(cond.Where); i++ {
+		c := cond.Where[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?' && n < len(cond.Args):
+			var ph string
+			ph, args = p.bindArgument(cond.Where, cond.Args[n], args, ctx)
+			b.WriteString(ph)
+			n++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), append(args, cond.Args[n:]...)
+}
+
+// quoteColumn quote a mapped column with the dialect, qualified names are
+// quoted part by part, and expressions are left as they are
+func (p *Parser) quoteColumn(column string) string {
+	return quoteIdentifier(p.Dialect, column)
+}
+
+func quoteIdentifier(d Dialect, name string) string {
+	if d == nil || !isColumn(name) {
+		return name
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = d.Quote(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// unquoteColumn strip identifier quotes, eg., "users"."id" => users.id
+func unquoteColumn(column string) string {
+	parts := strings.Split(column, ".")
+	for i, part := range parts {
+		if len(part) < 2 {
+			continue
+		}
+		switch part[0] {
+		case '"', '`':
+			if part[len(part)-1] == part[0] {
+				parts[i] = part[1 : len(part)-1]
+			}
+		case '[':
+			if part[len(part)-1] == ']' {
+				parts[i] = part[1 : len(part)-1]
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// listLen number of items of a list argument, -1 if not a list
+func listLen(arg interface{}) int {
+	if !isList(arg) {
+		return -1
+	}
+	return reflect.ValueOf(arg).Len()
+}
+
+// isList check if the argument is a list of values, []byte is a value
+func isList(arg interface{}) bool {
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Slice:
+		return v.Type().Elem().Kind() != reflect.Uint8
+	case reflect.Array:
+		return v.Type().Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
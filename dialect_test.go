@@ -0,0 +1,197 @@
+package djolar
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseDialect(t *testing.T) {
+	query := url.Values{
+		"q": {"n__eq__a|a__in__[1,2,3]"},
+		"s": {"-a,t"},
+		"g": {"n"},
+		"f": {"n,a__sum"},
+		"h": {"a__sum__gt__10"},
+	}
+
+	cases := []struct {
+		dialect Dialect
+		where   string
+		orderby string
+		groupby string
+		selects string
+		having  string
+	}{
+		{
+			Postgres,
+			`tenant_id = $1 AND "name" = $2 AND "age" IN ($3, $4, $5)`,
+			`"age" DESC,"users"."team" ASC`, `"name"`, `"name",SUM("age") AS "a__sum"`, `SUM("age") > $6`,
+		},
+		{
+			MySQL,
+			"tenant_id = ? AND `name` = ? AND `age` IN (?, ?, ?)",
+			"`age` DESC,`users`.`team` ASC", "`name`", "`name`,SUM(`age`) AS `a__sum`", "SUM(`age`) > ?",
+		},
+		{
+			SQLite,
+			`tenant_id = ? AND "name" = ? AND "age" IN (?, ?, ?)`,
+			`"age" DESC,"users"."team" ASC`, `"name"`, `"name",SUM("age") AS "a__sum"`, `SUM("age") > ?`,
+		},
+		{
+			SQLServer,
+			`tenant_id = @p1 AND [name] = @p2 AND [age] IN (@p3, @p4, @p5)`,
+			`[age] DESC,[users].[team] ASC`, `[name]`, `[name],SUM([age]) AS [a__sum]`, `SUM([age]) > @p6`,
+		},
+	}
+	for _, c := range cases {
+		p := NewParser()
+		p.Dialect = c.dialect
+		p.Metadata = MetaData{
+			QueryMapping:    map[string]string{"n": "name", "a": "age", "t": "users.team"},
+			FieldTypes:      map[string]FieldType{"a": TypeInt},
+			ForceConditions: []Condition{{Where: "tenant_id = ?", Args: []interface{}{7}}},
+		}
+		res := p.Parse(query)
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("exp: %v, got: %v", c.where, res.WhereClause.Where)
+		}
+		args := []interface{}{7, "a", int64(1), int64(2), int64(3)}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+			t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+		}
+		if res.OrderByClause != c.orderby {
+			t.Fatalf("exp: %v, got: %v", c.orderby, res.OrderByClause)
+		}
+		if res.GroupByClause != c.groupby {
+			t.Fatalf("exp: %v, got: %v", c.groupby, res.GroupByClause)
+		}
+		if res.SelectClause != c.selects {
+			t.Fatalf("exp: %v, got: %v", c.selects, res.SelectClause)
+		}
+		if res.HavingClause.Where != c.having {
+			t.Fatalf("exp: %v, got: %v", c.having, res.HavingClause.Where)
+		}
+		having := []interface{}{"10"}
+		if !reflect.DeepEqual(res.HavingClause.Arguments, having) {
+			t.Fatalf("exp: %v, got: %v", having, res.HavingClause.Arguments)
+		}
+	}
+}
+
+func TestParseDialectExpression(t *testing.T) {
+	p := NewParser()
+	p.Dialect = Postgres
+	p.Metadata = MetaData{QueryMapping: map[string]string{"l": "LOWER(name)"}}
+	res, _ := p.ParseQuery("q=l__eq__bob")
+	exp := "LOWER(name) = $1"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+}
+
+func TestBindCondition(t *testing.T) {
+	p := NewParser()
+	p.Dialect = Postgres
+	ctx := &parseContext{argc: 1}
+
+	where, args := p.bindCondition(Condition{
+		Where: "name <> '?' AND gender IN (?) AND age > ?",
+		Args:  []interface{}{[]int{1, 2}, 18},
+	}, ctx)
+	exp := "name <> '?' AND gender IN ($2, $3) AND age > $4"
+	if where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, where)
+	}
+	expArgs := []interface{}{1, 2, 18}
+	if !reflect.DeepEqual(args, expArgs) {
+		t.Fatalf("exp: %v, got: %v", expArgs, args)
+	}
+}
+
+func TestParseDialectCursor(t *testing.T) {
+	p := NewParser()
+	p.Dialect = Postgres
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"a": "age", "id": "id"},
+		FieldTypes:       map[string]FieldType{"a": TypeInt},
+		ForceConditions:  []Condition{{Where: "tenant_id = ?", Args: []interface{}{7}}},
+		CursorTiebreaker: "id",
+	}
+
+	res, _ := p.ParseQuery("s=-a")
+	if exp := `"age" DESC,"id" ASC`; res.OrderByClause != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.OrderByClause)
+	}
+	if exp := []string{"age", "id"}; !reflect.DeepEqual(res.CursorColumns, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, res.CursorColumns)
+	}
+
+	cursor, _ := res.NextCursor(18, 10)
+	res = p.Parse(url.Values{"s": {"-a"}, "cursor": {cursor}})
+	exp := `tenant_id = $1 AND ("age" < $2 OR "age" = $3 AND "id" > $4)`
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	if len(res.WhereClause.Arguments) != 4 {
+		t.Fatalf("exp: %v, got: %v", 4, len(res.WhereClause.Arguments))
+	}
+}
+
+func TestParseDialectCursorRowValues(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		where   string
+	}{
+		{Postgres, `tenant_id = $1 AND ("age", "id") > ($2, $3)`},
+		{MySQL, "tenant_id = ? AND (`age`, `id`) > (?, ?)"},
+		{SQLServer, `tenant_id = @p1 AND ([age] > @p2 OR [age] = @p3 AND [id] > @p4)`},
+	}
+	for _, c := range cases {
+		p := NewParser()
+		p.Dialect = c.dialect
+		p.Metadata = MetaData{
+			QueryMapping:     map[string]string{"a": "age", "id": "id"},
+			ForceConditions:  []Condition{{Where: "tenant_id = ?", Args: []interface{}{7}}},
+			CursorTiebreaker: "id",
+		}
+
+		res, _ := p.ParseQuery("s=a")
+		cursor, _ := res.NextCursor(18, 10)
+		res = p.Parse(url.Values{"s": {"a"}, "cursor": {cursor}})
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("exp: %v, got: %v", c.where, res.WhereClause.Where)
+		}
+	}
+
+	// a single column needs no row value
+	p := NewParser()
+	p.Dialect = SQLServer
+	p.Metadata = MetaData{
+		QueryMapping:     map[string]string{"id": "id"},
+		ForceConditions:  []Condition{{Where: "tenant_id = ?", Args: []interface{}{7}}},
+		CursorTiebreaker: "id",
+	}
+	res, _ := p.ParseQuery("")
+	cursor, _ := res.NextCursor(10)
+	res = p.Parse(url.Values{"cursor": {cursor}})
+	if exp := `tenant_id = @p1 AND [id] > @p2`; res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	cases := map[Dialect]string{
+		Postgres:  `"a""b"`,
+		MySQL:     "`a\"b`",
+		SQLServer: `[a"b]`,
+	}
+	for d, exp := range cases {
+		if got := d.Quote(`a"b`); got != exp {
+			t.Fatalf("exp: %v, got: %v", exp, got)
+		}
+	}
+	if got := SQLServer.Quote("a]b"); got != "[a]]b]" {
+		t.Fatalf("exp: %v, got: %v", "[a]]b]", got)
+	}
+}
@@ -0,0 +1,111 @@
+package djolar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Reason reason code of a rejected atom
+type Reason string
+
+const (
+	// ReasonMalformed the atom does not match `field__operator__value`,
+	// or the expression around it is not well formed
+	ReasonMalformed Reason = "malformed"
+	// ReasonUnknownField the field is not defined in QueryMapping
+	ReasonUnknownField Reason = "unknown_field"
+	// ReasonUnknownOperator the operator is not registered
+	ReasonUnknownOperator Reason = "unknown_operator"
+	// ReasonInvalidValue the value cannot be converted to the field type
+	ReasonInvalidValue Reason = "invalid_value"
+	// ReasonOperatorNotAllowed the operator is not allowed for the field,
+	// see MetaData.AllowedOperators
+	ReasonOperatorNotAllowed Reason = "operator_not_allowed"
+	// ReasonFieldNotAllowed the field is mapped but cannot be used in the
+	// parameter, eg., sorting on a field missing from SortableFields
+	ReasonFieldNotAllowed Reason = "field_not_allowed"
+	// ReasonUnknownAggregate the aggregate function is not defined in
+	// AggregateFunctions
+	ReasonUnknownAggregate Reason = "unknown_aggregate"
+	// ReasonLimitExceeded the query is over one of the parser Limits
+	ReasonLimitExceeded Reason = "limit_exceeded"
+	// ReasonUnsupported the function or option is valid in the syntax of
+	// the parameter but not supported, eg., the OData function length
+	ReasonUnsupported Reason = "unsupported"
+)
+
+// ErrLimitExceeded matched by errors.Is for errors caused by the parser
+// Limits
+var ErrLimitExceeded = errors.New("djolar: limit exceeded")
+
+// AtomError describe an atom rejected by the parser
+type AtomError struct {
+	// query parameter the atom comes from, eg., q
+	Param string
+	// byte offset of the atom in the (unescaped) parameter value, or index
+	// of the leaf or item of filter documents
+	Position int
+	// the rejected atom
+	Atom string
+	// query field and operator of the atom, if they could be extracted
+	Field    string
+	Operator string
+	Reason   Reason
+}
+
+func (e *AtomError) Error() string {
+	switch e.Reason {
+	case ReasonUnknownField:
+		return fmt.Sprintf("%s[%d]: unknown field %q", e.Param, e.Position, e.Field)
+	case ReasonUnknownOperator:
+		return fmt.Sprintf("%s[%d]: unknown operator %q", e.Param, e.Position, e.Operator)
+	case ReasonOperatorNotAllowed:
+		return fmt.Sprintf("%s[%d]: operator %q not allowed for field %q", e.Param, e.Position, e.Operator, e.Field)
+	case ReasonUnknownAggregate:
+		return fmt.Sprintf("%s[%d]: unknown aggregate function in %q", e.Param, e.Position, e.Field)
+	case ReasonFieldNotAllowed:
+		return fmt.Sprintf("%s[%d]: field %q not allowed", e.Param, e.Position, e.Field)
+	case ReasonInvalidValue:
+		return fmt.Sprintf("%s[%d]: invalid value for field %q in %q", e.Param, e.Position, e.Field, e.Atom)
+	case ReasonLimitExceeded:
+		return fmt.Sprintf("%s[%d]: limit exceeded", e.Param, e.Position)
+	case ReasonUnsupported:
+		return fmt.Sprintf("%s[%d]: %q not supported", e.Param, e.Position, e.Operator)
+	}
+	return fmt.Sprintf("%s[%d]: %s %q", e.Param, e.Position, e.Reason, e.Atom)
+}
+
+// fatal check if the error fails the parse even if the parser is not
+// strict, dropping unsupported parts would change the meaning of the query
+func (e *AtomError) fatal() bool {
+	return e.Reason == ReasonLimitExceeded || e.Reason == ReasonUnsupported
+}
+
+// Is match ErrLimitExceeded if the atom is over a limit
+func (e *AtomError) Is(target error) bool {
+	return target == ErrLimitExceeded && e.Reason == ReasonLimitExceeded
+}
+
+// ParseError returned by strict parsing, listing every rejected atom
+type ParseError struct {
+	Errors []*AtomError
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "djolar: invalid query: " + strings.Join(msgs, "; ")
+}
+
+// Is match ErrLimitExceeded if any atom is over a limit
+func (e *ParseError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if err.Is(target) {
+			return true
+		}
+	}
+	return false
+}
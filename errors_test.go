@@ -0,0 +1,108 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseStrictSuccess(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+		"n": "name",
+	}
+
+	qv, _ := url.ParseQuery("q=a__eq__1||n__co__x&s=-a&g=n&f=n,a__sum&h=a__sum__gt__1")
+	res, err := p.ParseStrict(qv)
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	exp := "age = ? OR name LIKE ? ESCAPE '!'"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+}
+
+func TestParseStrictReportsEveryAtom(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+	}
+
+	qv, _ := url.ParseQuery("q=a__eq__1|x__eq__2|a__zz__3|garbage&s=a,-y&g=z&f=w&h=a__sum__gt__1|b__count__gt__0")
+	res, err := p.ParseStrict(qv)
+	if res != nil {
+		t.Fatalf("exp: nil result, got: %v", res)
+	}
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("exp: *ParseError, got: %v", err)
+	}
+
+	exp := []*AtomError{
+		{Param: "q", Position: 9, Atom: "x__eq__2", Field: "x", Operator: "eq", Reason: ReasonUnknownField},
+		{Param: "q", Position: 18, Atom: "a__zz__3", Field: "a", Operator: "zz", Reason: ReasonUnknownOperator},
+		{Param: "q", Position: 27, Atom: "garbage", Reason: ReasonMalformed},
+		{Param: "s", Position: 2, Atom: "-y", Field: "y", Reason: ReasonUnknownField},
+		{Param: "g", Position: 0, Atom: "z", Field: "z", Reason: ReasonUnknownField},
+		{Param: "f", Position: 0, Atom: "w", Field: "w", Reason: ReasonUnknownField},
+		{Param: "h", Position: 14, Atom: "b__count__gt__0", Field: "b__count", Operator: "gt", Reason: ReasonUnknownField},
+	}
+	if !reflect.DeepEqual(perr.Errors, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, perr.Errors)
+	}
+}
+
+func TestParseStrictMalformedExpression(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+	}
+
+	qv, _ := url.ParseQuery("q=(a__eq__1)x|(a__eq__2")
+	_, err := p.ParseStrict(qv)
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("exp: *ParseError, got: %v", err)
+	}
+	exp := []*AtomError{
+		{Param: "q", Position: 10, Atom: "x", Reason: ReasonMalformed},
+		{Param: "q", Position: 12, Atom: "(", Reason: ReasonMalformed},
+	}
+	if !reflect.DeepEqual(perr.Errors, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, perr.Errors)
+	}
+}
+
+func TestParseQueryWithStrictOption(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+	}
+
+	// lenient by default
+	res, err := p.ParseQuery("q=b__eq__1")
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	if res.WhereClause.Where != "" {
+		t.Fatalf("exp: empty where, got: %v", res.WhereClause.Where)
+	}
+
+	p.Strict = true
+	_, err = p.ParseQuery("q=b__eq__1")
+	exp := "djolar: invalid query: q[0]: unknown field \"b\""
+	if err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, err)
+	}
+
+	_, err = p.ParseURI("http://abc.com?q=a__eq__1|a__xx__1")
+	exp = "djolar: invalid query: q[9]: unknown operator \"xx\""
+	if err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, err)
+	}
+}
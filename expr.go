@@ -0,0 +1,245 @@
+package djolar
+
+import "strings"
+
+// Boolean expression support for the `q` and `h` parameters.
+//
+// Grammar (AND binds tighter than OR):
+//
+// 	expr   = term { "||" term }
+// 	term   = factor { "|" factor }
+// 	factor = "!" factor | "(" expr ")" | atom
+// 	atom   = field "__" operator "__" value
+//
+// eg., q=(status__eq__open||assignee__eq__me)|!archived__eq__1
+// => (status = ? OR assignee = ?) AND NOT (archived = ?)
+//
+// A single `|` keeps its original meaning (AND). `||` used to be read as an
+// empty atom between two ANDed atoms, eg., a__eq__1||b__eq__2 was a = ? AND
+// b = ?, it is now an OR, so queries joining lists with empty items match
+// more rows than before.
+
+type exprKind int
+
+const (
+	exprAtom exprKind = iota
+	exprAnd
+	exprOr
+	exprNot
+)
+
+type exprNode struct {
+	kind     exprKind
+	children []*exprNode
+	// atom text and its byte offset in the parameter value, only for exprAtom
+	atom string
+	pos  int
+	// field, operator and value of the atom, given by front ends whose atoms
+	// are not written field__operator__value, eg., RSQL
+	parts *atomParts
+}
+
+type atomParts struct {
+	field string
+	op    string
+	value string
+	// type of the value when it is typed, eg., a JSON number
+	valueType FieldType
+	// reason of a malformed atom, ReasonMalformed if empty, and whether the
+	// front end stopped at it, the rest of the expression is lost, eg., OData
+	reason Reason
+	stop   bool
+}
+
+type exprParser struct {
+	src   string
+	pos   int
+	depth int
+	// text which could not be parsed, eg., unclosed parentheses
+	malformed []*exprNode
+}
+
+// parseExpr parse the boolean expression of a `q` or `h` parameter.
+// Text which is not part of the expression is skipped, and returned
+// as malformed atoms.
+func parseExpr(src string) (*exprNode, []*exprNode) {
+	p := &exprParser{src: src}
+	return p.parseOr(), p.malformed
+}
+
+// parseOr and parseAnd return the single operand as it is, so that a
+// plain atom does not allocate any group node
+func (p *exprParser) parseOr() *exprNode {
+	first := p.parseAnd()
+	if !strings.HasPrefix(p.src[p.pos:], "||") {
+		return first
+	}
+	node := &exprNode{kind: exprOr, children: []*exprNode{first}}
+	for strings.HasPrefix(p.src[p.pos:], "||") {
+		p.pos += 2
+		node.children = append(node.children, p.parseAnd())
+	}
+	return node
+}
+
+func (p *exprParser) parseAnd() *exprNode {
+	first := p.parseFactor()
+	p.skipGarbage()
+	if !p.atAnd() {
+		return first
+	}
+	node := &exprNode{kind: exprAnd, children: []*exprNode{first}}
+	for p.atAnd() {
+		p.pos++
+		node.children = append(node.children, p.parseFactor())
+		p.skipGarbage()
+	}
+	return node
+}
+
+// atAnd check if the next token is `|`
+func (p *exprParser) atAnd() bool {
+	return p.pos < len(p.src) && p.src[p.pos] == '|' && (p.pos+1 == len(p.src) || p.src[p.pos+1] != '|')
+}
+
+func (p *exprParser) parseFactor() *exprNode {
+	if p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '!':
+			p.pos++
+			return &exprNode{kind: exprNot, children: []*exprNode{p.parseFactor()}}
+		case '(':
+			start := p.pos
+			p.pos++
+			p.depth++
+			node := p.parseOr()
+			if p.pos < len(p.src) && p.src[p.pos] == ')' {
+				p.pos++
+			} else {
+				p.malformed = append(p.malformed, &exprNode{kind: exprAtom, atom: "(", pos: start})
+			}
+			p.depth--
+			return node
+		}
+	}
+	return p.parseAtom()
+}
+
+// parseAtom read an atom up to the next `|`, or up to the `)` closing the
+// enclosing group. Parentheses balanced inside the value belong to the value,
+// as do quoted and escaped characters.
+func (p *exprParser) parseAtom() *exprNode {
+	start := p.pos
+	nested := 0
+	quoted := false
+loop:
+	for ; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		if quoted && c != '"' && c != '\\' {
+			continue
+		}
+		switch c {
+		case '\\':
+			if p.pos+1 < len(p.src) {
+				p.pos++
+			}
+		case '"':
+			quoted = !quoted
+		case '|':
+			break loop
+		case '(':
+			nested++
+		case ')':
+			if nested > 0 {
+				nested--
+			} else if p.depth > 0 {
+				break loop
+			}
+		}
+	}
+	return &exprNode{kind: exprAtom, atom: p.src[start:p.pos], pos: start}
+}
+
+// skipGarbage drop anything following a group up to the next separator,
+// eg., the `x` in `(a__eq__1)x|b__eq__2`
+func (p *exprParser) skipGarbage() {
+	start := p.pos
+	for ; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		if c == '|' || (c == ')' && p.depth > 0) {
+			break
+		}
+	}
+	if p.pos > start {
+		p.malformed = append(p.malformed, &exprNode{kind: exprAtom, atom: p.src[start:p.pos], pos: start})
+	}
+}
+
+// size number of atoms of the expression, and its nesting depth
+func (n *exprNode) size() (atoms, depth int) {
+	if n.kind == exprAtom {
+		return 1, 0
+	}
+	for _, child := range n.children {
+		childAtoms, childDepth := child.size()
+		atoms += childAtoms
+		if childDepth > depth {
+			depth = childDepth
+		}
+	}
+	if n.kind == exprNot || len(n.children) > 1 {
+		depth++
+	}
+	return atoms, depth
+}
+
+// atomHandler build the SQL fragment of a single atom, appending its
+// arguments to the clause
+type atomHandler func(node *exprNode, clause *WhereClause) (string, bool)
+
+// renderExpr render the expression tree to SQL. Atoms rejected by the
+// handler are dropped, as are groups left without any atom.
+// It returns the kind of the outermost rendered operator, so the caller
+// knows whether parentheses are needed when joining with other conditions.
+func renderExpr(node *exprNode, clause *WhereClause, handle atomHandler) (string, exprKind, bool) {
+	switch node.kind {
+	case exprAtom:
+		where, ok := handle(node, clause)
+		return where, exprAtom, ok
+	case exprNot:
+		where, _, ok := renderExpr(node.children[0], clause, handle)
+		if !ok {
+			return "", exprNot, false
+		}
+		return "NOT (" + where + ")", exprNot, true
+	}
+
+	sep := " AND "
+	if node.kind == exprOr {
+		sep = " OR "
+	}
+	parts := make([]string, 0, len(node.children))
+	kinds := make([]exprKind, 0, len(node.children))
+	for _, child := range node.children {
+		where, childKind, ok := renderExpr(child, clause, handle)
+		if !ok {
+			continue
+		}
+		parts = append(parts, where)
+		kinds = append(kinds, childKind)
+	}
+	switch len(parts) {
+	case 0:
+		return "", node.kind, false
+	case 1:
+		return parts[0], kinds[0], true
+	}
+	if node.kind == exprAnd {
+		for i, kind := range kinds {
+			if kind == exprOr {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+	}
+	return strings.Join(parts, sep), node.kind, true
+}
@@ -0,0 +1,187 @@
+package djolar
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOrExpression(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, err := p.ParseQuery("q=a__eq__1||b__eq__2")
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+
+	exp := "a = ? OR b = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"1", "2"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseEmptyAtomIsOr(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	// a list joined with an empty item, which used to be ANDed
+	res, _ := p.ParseQuery("q=" + strings.Join([]string{"a__eq__1", "", "b__eq__2"}, "|"))
+
+	exp := "a = ? OR b = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+}
+
+func TestParseAndBindsTighterThanOr(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, _ := p.ParseQuery("q=a__eq__1|b__eq__2||c__eq__3")
+
+	exp := "a = ? AND b = ? OR c = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+}
+
+func TestParseGroupedExpression(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, _ := p.ParseQuery("q=(a__eq__1||b__eq__2)|!(c__gt__3||d__in__[x,y])")
+
+	exp := "(a = ? OR b = ?) AND NOT (c > ? OR d IN (?))"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"1", "2", "3", []string{"x", "y"}}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseNestedGroups(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, _ := p.ParseQuery("q=a__eq__1|(b__eq__2||(c__eq__3|d__eq__4))")
+
+	exp := "a = ? AND (b = ? OR c = ? AND d = ?)"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"1", "2", "3", "4"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseExpressionWithParenthesesInValue(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, _ := p.ParseQuery("q=(a__eq__f(x)||b__co__g(y))|c__eq__(y")
+
+	exp := "(a = ? OR b LIKE ? ESCAPE '!') AND c = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"f(x)", "%g(y)%", "(y"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseExpressionDropsInvalidAtoms(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, _ := p.ParseQuery("q=(x__eq__1||a__eq__1)|!(y__eq__2)|b__zz__3||c__eq__3")
+
+	exp := "a = ? OR c = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"1", "3"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseOrExpressionWithForceSearch(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+	p.Metadata.ForceSearch = map[string]interface{}{
+		"tenant_id = ?": 7,
+	}
+
+	res, _ := p.ParseQuery("q=a__eq__1||b__eq__2")
+
+	exp := "tenant_id = ? AND (a = ? OR b = ?)"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{7, "1", "2"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseUnbalancedExpression(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, _ := p.ParseQuery("q=(a__eq__1||b__eq__2")
+	exp := "a = ? OR b = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+
+	res, _ = p.ParseQuery("q=(a__eq__1)x|b__eq__2")
+	exp = "a = ? AND b = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+}
+
+func TestParseHavingOrExpression(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"},
+	}
+
+	res, _ := p.ParseQuery("h=a__sum__gt__1||b__count__eq__0")
+
+	exp := "SUM(a) > ? OR COUNT(b) = ?"
+	if res.HavingClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.HavingClause.Where)
+	}
+	args := []interface{}{"1", "0"}
+	if !reflect.DeepEqual(res.HavingClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.HavingClause.Arguments)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/enix223/go-djolar/gormv1
+
+go 1.16
+
+replace github.com/enix223/go-djolar => ../
+
+require (
+	github.com/enix223/go-djolar v0.0.0
+	github.com/jinzhu/gorm v1.9.16
+)
@@ -0,0 +1,124 @@
+package gormv1
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	djolar "github.com/enix223/go-djolar"
+	"github.com/jinzhu/gorm"
+)
+
+type user struct {
+	ID   uint
+	Name string
+	Age  int
+}
+
+// recorder sql connection recording the last query
+type recorder struct {
+	query string
+	args  []interface{}
+}
+
+var errRecorded = errors.New("recorded")
+
+func (r *recorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	r.query, r.args = query, args
+	return nil, errRecorded
+}
+
+func (r *recorder) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errRecorded
+}
+
+func (r *recorder) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	r.query, r.args = query, args
+	return nil, errRecorded
+}
+
+func (r *recorder) QueryRow(query string, args ...interface{}) *sql.Row {
+	r.query, r.args = query, args
+	return nil
+}
+
+func run(t *testing.T, scope func(*gorm.DB) *gorm.DB) *recorder {
+	r := &recorder{}
+	db, err := gorm.Open("common", r)
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	db.LogMode(false)
+	var users []user
+	db.Scopes(scope).Find(&users)
+	return r
+}
+
+func TestScope(t *testing.T) {
+	p := djolar.NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+	res, _ := p.ParseQuery("q=n__co__en|a__gt__18&s=-a&limit=10&offset=20")
+
+	r := run(t, Scope(res))
+	exp := `SELECT * FROM "users"  WHERE (name LIKE ? ESCAPE '!' AND age > ?) ORDER BY age DESC LIMIT 10 OFFSET 20`
+	if r.query != exp {
+		t.Fatalf("exp: %v, got: %v", exp, r.query)
+	}
+	args := []interface{}{"%en%", "18"}
+	if !reflect.DeepEqual(r.args, args) {
+		t.Fatalf("exp: %v, got: %v", args, r.args)
+	}
+}
+
+func TestScopeAggregate(t *testing.T) {
+	p := djolar.NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+	res, _ := p.ParseQuery("f=n,a__sum&g=n&h=a__sum__gt__100")
+
+	r := run(t, Scope(res))
+	exp := `SELECT name,SUM(age) AS a__sum FROM "users"   GROUP BY name HAVING (SUM(age) > ?)`
+	if r.query != exp {
+		t.Fatalf("exp: %v, got: %v", exp, r.query)
+	}
+	args := []interface{}{"100"}
+	if !reflect.DeepEqual(r.args, args) {
+		t.Fatalf("exp: %v, got: %v", args, r.args)
+	}
+}
+
+func TestScopeEmpty(t *testing.T) {
+	res, _ := djolar.NewParser().ParseQuery("")
+
+	exp := `SELECT * FROM "users"  `
+	if r := run(t, Scope(res)); r.query != exp {
+		t.Fatalf("exp: %v, got: %v", exp, r.query)
+	}
+	if r := run(t, Scope(nil)); r.query != exp {
+		t.Fatalf("exp: %v, got: %v", exp, r.query)
+	}
+}
+
+func TestScopeDialect(t *testing.T) {
+	p := djolar.NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+
+	// numbered placeholders are not bound by gorm
+	p.Dialect = djolar.Postgres
+	res, _ := p.ParseQuery("q=a__gt__18")
+	db, _ := gorm.Open("common", &recorder{})
+	db.LogMode(false)
+	var users []user
+	if err := db.Scopes(Scope(res)).Find(&users).Error; !errors.Is(err, ErrPlaceholders) {
+		t.Fatalf("exp: %v, got: %v", ErrPlaceholders, err)
+	}
+
+	// ? placeholders are
+	p.Dialect = djolar.MySQL
+	res, _ = p.ParseQuery("q=a__gt__18")
+	r := run(t, Scope(res))
+	exp := "SELECT * FROM \"users\"  WHERE (`age` > ?)"
+	if r.query != exp {
+		t.Fatalf("exp: %v, got: %v", exp, r.query)
+	}
+}
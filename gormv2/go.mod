@@ -0,0 +1,16 @@
+module github.com/enix223/go-djolar/gormv2
+
+go 1.18
+
+replace github.com/enix223/go-djolar => ../
+
+require (
+	github.com/enix223/go-djolar v0.0.0
+	gorm.io/gorm v1.25.12
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
@@ -0,0 +1,61 @@
+// Package gormv2 apply djolar parse results to gorm.io/gorm queries
+package gormv2
+
+import (
+	"errors"
+
+	djolar "github.com/enix223/go-djolar"
+	"gorm.io/gorm"
+)
+
+// ErrPlaceholders added to the db by Apply when the parse result has
+// numbered placeholders, eg., $1, gorm only binds ?
+var ErrPlaceholders = errors.New("djolar: gorm needs ? placeholders, the parser Dialect numbers them")
+
+// Scope gorm scope applying the parse result, eg.,
+//
+//	res := parser.Parse(r.URL.Query())
+//	db.Scopes(gormv2.Scope(res)).Find(&users)
+func Scope(res *djolar.ParseResult) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return Apply(db, res)
+	}
+}
+
+// Apply apply the select, where, group by, having, order by, limit and
+// offset clauses of the parse result to db. Empty clauses are skipped.
+//
+// The parser must have no Dialect, or one with ? placeholders, eg.,
+// djolar.MySQL. Results with numbered placeholders, eg., djolar.Postgres,
+// are not applied and ErrPlaceholders is added to db.
+func Apply(db *gorm.DB, res *djolar.ParseResult) *gorm.DB {
+	if res == nil {
+		return db
+	}
+	if d := res.Dialect(); d != nil && d.Placeholder(1) != "?" {
+		db.AddError(ErrPlaceholders)
+		return db
+	}
+	if len(res.SelectClause) > 0 {
+		db = db.Select(res.SelectClause)
+	}
+	if res.WhereClause != nil && len(res.WhereClause.Where) > 0 {
+		db = db.Where(res.WhereClause.Where, res.WhereClause.Arguments...)
+	}
+	if len(res.GroupByClause) > 0 {
+		db = db.Group(res.GroupByClause)
+	}
+	if res.HavingClause != nil && len(res.HavingClause.Where) > 0 {
+		db = db.Having(res.HavingClause.Where, res.HavingClause.Arguments...)
+	}
+	if len(res.OrderByClause) > 0 {
+		db = db.Order(res.OrderByClause)
+	}
+	if res.Limit > 0 {
+		db = db.Limit(res.Limit)
+	}
+	if res.Offset > 0 {
+		db = db.Offset(res.Offset)
+	}
+	return db
+}
@@ -0,0 +1,96 @@
+package gormv2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	djolar "github.com/enix223/go-djolar"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+type user struct {
+	ID   uint
+	Name string
+	Age  int
+}
+
+func dryRun(t *testing.T, scope func(*gorm.DB) *gorm.DB) *gorm.Statement {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	var users []user
+	return db.Scopes(scope).Find(&users).Statement
+}
+
+func TestScope(t *testing.T) {
+	p := djolar.NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+	res, _ := p.ParseQuery("q=n__co__en|a__gt__18&s=-a&limit=10&offset=20")
+
+	stmt := dryRun(t, Scope(res))
+	exp := "SELECT * FROM `users` WHERE name LIKE ? ESCAPE '!' AND age > ? ORDER BY age DESC LIMIT ? OFFSET ?"
+	if stmt.SQL.String() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, stmt.SQL.String())
+	}
+	args := []interface{}{"%en%", "18", 10, 20}
+	if !reflect.DeepEqual(stmt.Vars, args) {
+		t.Fatalf("exp: %v, got: %v", args, stmt.Vars)
+	}
+}
+
+func TestScopeAggregate(t *testing.T) {
+	p := djolar.NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+	res, _ := p.ParseQuery("f=n,a__sum&g=n&h=a__sum__gt__100")
+
+	stmt := dryRun(t, Scope(res))
+	exp := "SELECT name,SUM(age) AS a__sum FROM `users` GROUP BY `name` HAVING SUM(age) > ?"
+	if stmt.SQL.String() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, stmt.SQL.String())
+	}
+	args := []interface{}{"100"}
+	if !reflect.DeepEqual(stmt.Vars, args) {
+		t.Fatalf("exp: %v, got: %v", args, stmt.Vars)
+	}
+}
+
+func TestScopeEmpty(t *testing.T) {
+	res, _ := djolar.NewParser().ParseQuery("")
+
+	stmt := dryRun(t, Scope(res))
+	exp := "SELECT * FROM `users`"
+	if stmt.SQL.String() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, stmt.SQL.String())
+	}
+
+	stmt = dryRun(t, Scope(nil))
+	if stmt.SQL.String() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, stmt.SQL.String())
+	}
+}
+
+func TestScopeDialect(t *testing.T) {
+	p := djolar.NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+
+	// numbered placeholders are not bound by gorm
+	p.Dialect = djolar.Postgres
+	res, _ := p.ParseQuery("q=a__gt__18")
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	var users []user
+	if err := db.Scopes(Scope(res)).Find(&users).Error; !errors.Is(err, ErrPlaceholders) {
+		t.Fatalf("exp: %v, got: %v", ErrPlaceholders, err)
+	}
+
+	// ? placeholders are
+	p.Dialect = djolar.MySQL
+	res, _ = p.ParseQuery("q=a__gt__18")
+	stmt := dryRun(t, Scope(res))
+	exp := "SELECT * FROM `users` WHERE `age` > ?"
+	if stmt.SQL.String() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, stmt.SQL.String())
+	}
+}
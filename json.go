@@ -0,0 +1,300 @@
+package djolar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FilterDocument JSON body of search endpoints, the counterpart of the
+// query parameters, eg.,
+//
+//	{
+//		"filter": {"and": [
+//			{"field": "name", "op": "co", "value": "enix"},
+//			{"or": [
+//				{"field": "age", "op": "gt", "value": 18},
+//				{"not": {"field": "status", "op": "in", "value": ["a", "b"]}}
+//			]}
+//		]},
+//		"sort": ["-age", "name"],
+//		"limit": 20
+//	}
+//	=> name LIKE ? ESCAPE '!' AND (age > ? OR NOT (status IN (?)))
+//	=> ["%enix%", 18, ["a", "b"]]
+//
+// Sort, Group and Select items are written as in the s, g and f
+// parameters. Errors are reported with the JSON member names as parameters,
+// eg., filter, and the leaves are numbered in document order as positions.
+type FilterDocument struct {
+	Filter  *FilterNode `json:"filter,omitempty"`
+	Sort    []string    `json:"sort,omitempty"`
+	Group   []string    `json:"group,omitempty"`
+	Select  []string    `json:"select,omitempty"`
+	Having  *FilterNode `json:"having,omitempty"`
+	Limit   *int        `json:"limit,omitempty"`
+	Offset  *int        `json:"offset,omitempty"`
+	Page    *int        `json:"page,omitempty"`
+	PerPage *int        `json:"per_page,omitempty"`
+	Cursor  string      `json:"cursor,omitempty"`
+}
+
+// FilterNode node of a filter tree, either a group with exactly one of
+// And, Or and Not, or a leaf with Field, Op and Value.
+//
+// Values are typed, JSON numbers and booleans are bound as int64, float64
+// and bool when the field has no FieldTypes entry, null (Null in Go) stands
+// for SQL NULL, and arrays are the values of the list operators, eg., in,
+// bt. Leaves without value are malformed, except for the operators without
+// argument, eg., isnull.
+type FilterNode struct {
+	And []*FilterNode `json:"and,omitempty"`
+	Or  []*FilterNode `json:"or,omitempty"`
+	Not *FilterNode   `json:"not,omitempty"`
+
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Null value of a FilterNode standing for SQL NULL, JSON null is decoded as
+// Null so that it is told apart from a missing value
+var Null = nullValue{}
+
+type nullValue struct{}
+
+func (nullValue) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON decode a node, a null value is decoded as Null
+func (n *FilterNode) UnmarshalJSON(data []byte) error {
+	type node FilterNode
+	var raw struct {
+		node
+		Value json.RawMessage `json:"value"`
+	}
+	if err := decodeJSON(data, &raw); err != nil {
+		return err
+	}
+	*n = FilterNode(raw.node)
+	n.Value = nil
+	switch {
+	case raw.Value == nil:
+		return nil
+	case string(raw.Value) == "null":
+		n.Value = Null
+		return nil
+	}
+	return decodeJSON(raw.Value, &n.Value)
+}
+
+// decodeJSON decode a filter document, numbers are kept as json.Number and
+// unknown members are rejected
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// documentNames parameter names of the errors of filter documents
+var documentNames = ParamNames{
+	Query:   "filter",
+	Sort:    "sort",
+	Group:   "group",
+	Select:  "select",
+	Having:  "having",
+	Limit:   "limit",
+	Offset:  "offset",
+	Page:    "page",
+	PerPage: "per_page",
+	Cursor:  "cursor",
+}
+
+// ParseJSON parse a JSON filter document, see FilterDocument. Unknown
+// members are rejected.
+func (p *Parser) ParseJSON(data []byte) (*ParseResult, error) {
+	var doc FilterDocument
+	if err := decodeJSON(data, &doc); err != nil {
+		return nil, fmt.Errorf("djolar: invalid filter document: %w", err)
+	}
+	return p.ParseDocument(&doc)
+}
+
+// ParseDocument parse a filter document, invalid nodes are dropped unless
+// the parser is strict, as with ParseQuery.
+func (p *Parser) ParseDocument(doc *FilterDocument) (*ParseResult, error) {
+	if doc == nil {
+		return nil, errors.New("djolar: nil filter document")
+	}
+	query := url.Values{}
+	for name, value := range map[string]*int{
+		documentNames.Limit:   doc.Limit,
+		documentNames.Offset:  doc.Offset,
+		documentNames.Page:    doc.Page,
+		documentNames.PerPage: doc.PerPage,
+	} {
+		if value != nil {
+			query.Set(name, strconv.Itoa(*value))
+		}
+	}
+	if doc.Cursor != "" {
+		query.Set(documentNames.Cursor, doc.Cursor)
+	}
+	return p.parseValues(query, &parseContext{names: documentNames, doc: doc})
+}
+
+// renderDocument render a filter tree of a document
+func (p *Parser) renderDocument(param string, node *FilterNode, clause *WhereClause, resolve fieldResolver, ctx *parseContext) (string, exprKind, bool) {
+	n := 0
+	var malformed []*exprNode
+	expr := p.documentTree(node, &n, &malformed)
+	if expr == nil {
+		expr = &exprNode{kind: exprAtom}
+	}
+	text, _ := json.Marshal(node)
+	return p.renderTree(param, string(text), expr, malformed, clause, resolve, ctx)
+}
+
+// documentTree convert a filter node to an expression tree, n counts the
+// leaves. Invalid nodes are added to malformed and dropped from the tree.
+func (p *Parser) documentTree(node *FilterNode, n *int, malformed *[]*exprNode) *exprNode {
+	if node == nil {
+		*malformed = append(*malformed, &exprNode{kind: exprAtom, atom: "null", pos: *n})
+		*n++
+		return nil
+	}
+
+	set := 0
+	for _, ok := range []bool{node.And != nil, node.Or != nil, node.Not != nil, node.Field != "" || node.Op != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 || (node.Not == nil && node.Field == "" && node.Op == "" && len(node.And)+len(node.Or) == 0) {
+		*malformed = append(*malformed, &exprNode{kind: exprAtom, atom: nodeText(node), pos: *n})
+		*n++
+		return nil
+	}
+
+	switch {
+	case node.Not != nil:
+		child := p.documentTree(node.Not, n, malformed)
+		if child == nil {
+			return nil
+		}
+		return &exprNode{kind: exprNot, children: []*exprNode{child}}
+	case node.And != nil || node.Or != nil:
+		kind, nodes := exprAnd, node.And
+		if node.Or != nil {
+			kind, nodes = exprOr, node.Or
+		}
+		group := &exprNode{kind: kind}
+		for _, child := range nodes {
+			if expr := p.documentTree(child, n, malformed); expr != nil {
+				group.children = append(group.children, expr)
+			}
+		}
+		switch len(group.children) {
+		case 0:
+			return nil
+		case 1:
+			return group.children[0]
+		}
+		return group
+	}
+
+	leaf := &exprNode{kind: exprAtom, atom: nodeText(node), pos: *n}
+	*n++
+	op, ok := p.Operator(node.Op)
+	if node.Value == nil && !(ok && op.NoArgument) {
+		leaf.parts = &atomParts{field: node.Field, op: node.Op}
+		*malformed = append(*malformed, leaf)
+		return nil
+	}
+	var value string
+	var valueType FieldType
+	valid := true
+	if node.Value != nil {
+		value, valueType, valid = encodeValue(node.Value, ok && (op.List || op.Range))
+	}
+	leaf.parts = &atomParts{field: node.Field, op: node.Op, value: value, valueType: valueType}
+	if !valid {
+		leaf.parts.reason = ReasonInvalidValue
+		*malformed = append(*malformed, leaf)
+		return nil
+	}
+	return leaf
+}
+
+// nodeText JSON text of a node, reported as the atom of its errors
+func nodeText(node *FilterNode) string {
+	text, err := json.Marshal(node)
+	if err != nil {
+		return fmt.Sprint(node)
+	}
+	return string(text)
+}
+
+// encodeValue write a JSON value with the quoting of the atoms, along with
+// the type of typed values. Arrays are only valid for list operators.
+func encodeValue(value interface{}, list bool) (string, FieldType, bool) {
+	switch v := value.(type) {
+	case nil, nullValue:
+		return NullLiteral, "", true
+	case string:
+		return `"` + escapeValue(v) + `"`, "", true
+	case bool:
+		return strconv.FormatBool(v), TypeBool, true
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return v.String(), TypeInt, true
+		}
+		if _, err := v.Float64(); err == nil {
+			return v.String(), TypeFloat, true
+		}
+		return "", "", false
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), TypeInt, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), TypeInt, true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), TypeFloat, true
+	case reflect.Slice, reflect.Array:
+		if !list {
+			return "", "", false
+		}
+		items := make([]string, rv.Len())
+		var listType FieldType
+		typed := false
+		for i := range items {
+			item, itemType, ok := encodeValue(rv.Index(i).Interface(), false)
+			if !ok {
+				return "", "", false
+			}
+			items[i] = item
+			// the items share a type, integers are widened to floats
+			switch {
+			case item == NullLiteral:
+			case !typed || listType == itemType:
+				listType, typed = itemType, true
+			case listType == TypeInt && itemType == TypeFloat, listType == TypeFloat && itemType == TypeInt:
+				listType = TypeFloat
+			default:
+				listType = ""
+			}
+		}
+		return "[" + strings.Join(items, ",") + "]", listType, true
+	}
+	return "", "", false
+}
@@ -0,0 +1,198 @@
+package djolar
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONFilter(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{
+			"name":    "name",
+			"age":     "age",
+			"score":   "score",
+			"status":  "status",
+			"active":  "active",
+			"deleted": "deleted_at",
+		},
+		FieldTypes:         map[string]FieldType{"age": TypeInt, "status": TypeString},
+		SortableFields:     []string{"name", "age"},
+		GroupableFields:    []string{"status"},
+		AggregatableFields: []string{"age"},
+	}
+
+	cases := []struct {
+		filter string
+		where  string
+		args   []interface{}
+	}{
+		{`{"field": "name", "op": "eq", "value": "enix"}`, "name = ?", []interface{}{"enix"}},
+		{
+			`{"and": [{"field": "name", "op": "co", "value": "a_b"}, {"or": [{"field": "age", "op": "gt", "value": 18}, {"not": {"field": "status", "op": "in", "value": ["a", "b"]}}]}]}`,
+			"name LIKE ? ESCAPE '!' AND (age > ? OR NOT (status IN (?)))",
+			[]interface{}{"%a!_b%", int64(18), []interface{}{"a", "b"}},
+		},
+		{`{"and": [{"field": "score", "op": "gte", "value": 1.5}, {"field": "active", "op": "eq", "value": true}]}`, "score >= ? AND active = ?", []interface{}{1.5, true}},
+		{`{"field": "score", "op": "in", "value": [1, 2.5]}`, "score IN (?)", []interface{}{[]interface{}{float64(1), 2.5}}},
+		{`{"field": "age", "op": "bt", "value": [18, 30]}`, "age BETWEEN ? AND ?", []interface{}{int64(18), int64(30)}},
+		{`{"field": "status", "op": "in", "value": [1, 2]}`, "status IN (?)", []interface{}{[]interface{}{"1", "2"}}},
+		{`{"field": "name", "op": "eq", "value": "a, \"b\" | c"}`, "name = ?", []interface{}{`a, "b" | c`}},
+		{`{"and": [{"field": "deleted", "op": "eq", "value": null}, {"field": "name", "op": "eq", "value": "$null"}]}`, "deleted_at IS NULL AND name = ?", []interface{}{"$null"}},
+		{`{"field": "name", "op": "co", "value": 12}`, "name LIKE ? ESCAPE '!'", []interface{}{"%12%"}},
+		{`{"and": [{"field": "deleted", "op": "isnull"}, {"field": "name", "op": "in", "value": ["a", null]}]}`, "deleted_at IS NULL AND (name IN (?) OR name IS NULL)", []interface{}{[]string{"a"}}},
+		{`{"and": [{"field": "deleted", "op": "isnull", "value": false}, {"field": "name", "op": "isnull", "value": true}]}`, "NOT (deleted_at IS NULL) AND name IS NULL", []interface{}{}},
+		{`{"or": [{"field": "x", "op": "eq", "value": 1}, {"field": "age", "op": "eq", "value": 1}]}`, "age = ?", []interface{}{int64(1)}},
+	}
+	for _, c := range cases {
+		res, err := p.ParseJSON([]byte(`{"filter": ` + c.filter + `}`))
+		if err != nil {
+			t.Fatalf("%s exp: nil, got: %v", c.filter, err)
+		}
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("%s exp: %v, got: %v", c.filter, c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("%s exp: %#v, got: %#v", c.filter, c.args, res.WhereClause.Arguments)
+		}
+	}
+}
+
+func TestJSONDocument(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{
+			"name":    "name",
+			"age":     "age",
+			"score":   "score",
+			"status":  "status",
+			"active":  "active",
+			"deleted": "deleted_at",
+		},
+		FieldTypes:         map[string]FieldType{"age": TypeInt, "status": TypeString},
+		SortableFields:     []string{"name", "age"},
+		GroupableFields:    []string{"status"},
+		AggregatableFields: []string{"age"},
+		DefaultOrderBy:     []string{"id ASC"},
+	}
+
+	exp, _ := p.ParseQuery(`q=name__eq__"a,b"|age__gt__18&s=-age,name&g=status&f=status,age__sum&h=age__sum__gt__100&limit=20&offset=40`)
+	res, err := p.ParseJSON([]byte(`{
+		"filter": {"and": [{"field": "name", "op": "eq", "value": "a,b"}, {"field": "age", "op": "gt", "value": 18}]},
+		"sort": ["-age", "name"],
+		"group": ["status"],
+		"select": ["status", "age__sum"],
+		"having": {"field": "age__sum", "op": "gt", "value": "100"},
+		"limit": 20,
+		"offset": 40
+	}`))
+	if exp.HavingClause.Where != "SUM(age) > ?" {
+		t.Fatalf("exp: SUM(age) > ?, got: %v", exp.HavingClause.Where)
+	}
+	if err != nil || !reflect.DeepEqual(res, exp) {
+		t.Fatalf("exp: %v, got: %v %v", exp, res, err)
+	}
+
+	// built in Go, values need not be decoded from JSON
+	res, err = p.ParseDocument(&FilterDocument{
+		Filter: &FilterNode{Or: []*FilterNode{
+			{Field: "age", Op: "in", Value: []int{1, 2}},
+			{Field: "score", Op: "lt", Value: float32(0.5)},
+			{Field: "deleted", Op: "eq", Value: Null},
+		}},
+		Group:   []string{"status"},
+		Having:  &FilterNode{Field: "age__sum", Op: "gt", Value: 100},
+		Page:    intPtr(3),
+		PerPage: intPtr(10),
+	})
+	if err != nil {
+		t.Fatalf("exp: nil, got: %v", err)
+	}
+	args := []interface{}{[]interface{}{int64(1), int64(2)}, 0.5}
+	if res.WhereClause.Where != "age IN (?) OR score < ? OR deleted_at IS NULL" || !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v %v", args, res.WhereClause.Where, res.WhereClause.Arguments)
+	}
+	if !reflect.DeepEqual(res.HavingClause.Arguments, []interface{}{int64(100)}) {
+		t.Fatalf("exp: [100], got: %#v", res.HavingClause.Arguments)
+	}
+	if res.Limit != 10 || res.Offset != 20 || res.OrderByClause != "id ASC" {
+		t.Fatalf("exp: 10 20 id ASC, got: %v %v %v", res.Limit, res.Offset, res.OrderByClause)
+	}
+
+	// the default conditions apply without filter
+	p.Metadata.DefaultConditions = []Condition{{Where: "deleted_at IS NULL"}}
+	if res, _ := p.ParseJSON([]byte(`{}`)); res.WhereClause.Where != "deleted_at IS NULL" {
+		t.Fatalf("exp: deleted_at IS NULL, got: %v", res.WhereClause.Where)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{
+			"name":    "name",
+			"age":     "age",
+			"score":   "score",
+			"status":  "status",
+			"active":  "active",
+			"deleted": "deleted_at",
+		},
+		FieldTypes:         map[string]FieldType{"age": TypeInt, "status": TypeString},
+		SortableFields:     []string{"name", "age"},
+		GroupableFields:    []string{"status"},
+		AggregatableFields: []string{"age"},
+	}
+	p.Strict = true
+
+	cases := []struct {
+		doc string
+		err *AtomError
+	}{
+		{`{"filter": {"field": "x", "op": "eq", "value": 1}}`, &AtomError{Param: "filter", Atom: `{"field":"x","op":"eq","value":1}`, Field: "x", Operator: "eq", Reason: ReasonUnknownField}},
+		{`{"filter": {"and": [{"field": "age", "op": "eq", "value": 1}, {"field": "age", "op": "xx", "value": 1}]}}`, &AtomError{Param: "filter", Position: 1, Atom: `{"field":"age","op":"xx","value":1}`, Field: "age", Operator: "xx", Reason: ReasonUnknownOperator}},
+		{`{"filter": {"field": "age", "op": "eq", "value": [1, 2]}}`, &AtomError{Param: "filter", Atom: `{"field":"age","op":"eq","value":[1,2]}`, Field: "age", Operator: "eq", Reason: ReasonInvalidValue}},
+		{`{"filter": {"field": "age", "op": "in", "value": [[1]]}}`, &AtomError{Param: "filter", Atom: `{"field":"age","op":"in","value":[[1]]}`, Field: "age", Operator: "in", Reason: ReasonInvalidValue}},
+		{`{"filter": {"field": "age", "op": "eq", "value": {"a": 1}}}`, &AtomError{Param: "filter", Atom: `{"field":"age","op":"eq","value":{"a":1}}`, Field: "age", Operator: "eq", Reason: ReasonInvalidValue}},
+		{`{"filter": {"field": "status", "op": "gt", "value": null}}`, &AtomError{Param: "filter", Atom: `{"field":"status","op":"gt","value":null}`, Field: "status", Operator: "gt", Reason: ReasonInvalidValue}},
+		{`{"filter": {"field": "name", "op": "eq"}}`, &AtomError{Param: "filter", Atom: `{"field":"name","op":"eq"}`, Field: "name", Operator: "eq", Reason: ReasonMalformed}},
+		{`{"filter": {"and": [{"field": "age", "op": "eq", "value": 1}], "field": "name"}}`, &AtomError{Param: "filter", Atom: `{"and":[{"field":"age","op":"eq","value":1}],"field":"name"}`, Reason: ReasonMalformed}},
+		{`{"filter": {"or": []}}`, &AtomError{Param: "filter", Atom: `{}`, Reason: ReasonMalformed}},
+		{`{"filter": {"not": {"and": [null]}}}`, &AtomError{Param: "filter", Atom: "null", Reason: ReasonMalformed}},
+		{`{"sort": ["age", "x"]}`, &AtomError{Param: "sort", Position: 1, Atom: "x", Field: "x", Reason: ReasonUnknownField}},
+		{`{"limit": -1}`, &AtomError{Param: "limit", Atom: "-1", Field: "limit", Reason: ReasonInvalidValue}},
+	}
+	for _, c := range cases {
+		_, err := p.ParseJSON([]byte(c.doc))
+		var perr *ParseError
+		if !errors.As(err, &perr) || len(perr.Errors) != 1 || !reflect.DeepEqual(perr.Errors[0], c.err) {
+			t.Fatalf("%s exp: %v, got: %v", c.doc, c.err, err)
+		}
+	}
+
+	if _, err := p.ParseDocument(nil); err == nil {
+		t.Fatalf("exp: err for nil document, got: nil")
+	}
+
+	// not a filter document
+	for _, doc := range []string{`{"filter": {"field": "age", "value": 1, "x": 2}}`, `{"limit": "1"}`, `[`} {
+		_, err := p.ParseJSON([]byte(doc))
+		if err == nil || !strings.HasPrefix(err.Error(), "djolar: invalid filter document: ") {
+			t.Fatalf("%s exp: invalid filter document, got: %v", doc, err)
+		}
+	}
+
+	// over the limits even if not strict
+	p.Strict = false
+	p.Limits.MaxConditions = 1
+	_, err := p.ParseJSON([]byte(`{"filter": {"or": [{"field": "age", "op": "eq", "value": 1}, {"field": "age", "op": "eq", "value": 2}]}}`))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Errors[0].Param != "filter" || perr.Errors[0].Reason != ReasonLimitExceeded {
+		t.Fatalf("exp: filter limit exceeded, got: %v", err)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
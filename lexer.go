@@ -0,0 +1,109 @@
+package djolar
+
+import "strings"
+
+// Hand-written scanners used instead of regular expressions, so that
+// parsing a request does not run the regexp engine.
+
+// isWordChar check if the byte is an ASCII letter, digit or underscore
+func isWordChar(c byte) bool {
+	return c == '_' || isDigit(c) || isLetter(c)
+}
+
+// isWord check if the string is a non empty run of word characters,
+// eg., an operator name
+func isWord(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWordChar(s[i]) {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// isIdentifier check if the string is a word not starting with a digit,
+// eg., an aggregate alias or a function name
+func isIdentifier(s string) bool {
+	return isWord(s) && !isDigit(s[0])
+}
+
+// isColumn check if the string is an identifier, or identifiers separated
+// by dots, eg., users.id
+func isColumn(s string) bool {
+	for {
+		i := strings.IndexByte(s, '.')
+		if i < 0 {
+			return isIdentifier(s)
+		}
+		if !isIdentifier(s[:i]) {
+			return false
+		}
+		s = s[i+1:]
+	}
+}
+
+// matchAtom split an atom as `field__operator__value` without checking the
+// operator, the longest field and operator win, eg., a__b__c__d => a__b, c, d
+func matchAtom(atom string) (field, op, value string, ok bool) {
+	end := 0
+	for end < len(atom) && isWordChar(atom[end]) {
+		end++
+	}
+	// field and operator are words, and so are the separators
+	for i := end - 2; i > 0; i-- {
+		if atom[i] != '_' || atom[i+1] != '_' {
+			continue
+		}
+		for j := end - 2; j > i+2; j-- {
+			if atom[j] == '_' && atom[j+1] == '_' {
+				return atom[:i], atom[i+2 : j], atom[j+2:], true
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+// splitItems call fn with each comma separated item of the parameter, and
+// its byte offset, until fn returns false
+func splitItems(param string, fn func(i, pos int, item string) bool) {
+	pos := 0
+	for i := 0; ; i++ {
+		end := strings.IndexByte(param[pos:], ',')
+		if end < 0 {
+			fn(i, pos, param[pos:])
+			return
+		}
+		if !fn(i, pos, param[pos:pos+end]) {
+			return
+		}
+		pos += end + 1
+	}
+}
+
+// paramList items of a list parameter, either comma separated, eg., s=-a,n,
+// or the items of a JSON array, whose positions are their indexes
+type paramList struct {
+	value string
+	items []string
+}
+
+// each call fn with each item and its position, until fn returns false
+func (l paramList) each(fn func(i, pos int, item string) bool) {
+	if l.items == nil {
+		splitItems(l.value, fn)
+		return
+	}
+	for i, item := range l.items {
+		if !fn(i, i, item) {
+			return
+		}
+	}
+}
+
+// rest the items from the i-th one, at pos
+func (l paramList) rest(i, pos int) string {
+	if l.items == nil {
+		return l.value[pos:]
+	}
+	return strings.Join(l.items[i:], ",")
+}
@@ -0,0 +1,65 @@
+package djolar
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMatchAtom(t *testing.T) {
+	// matchAtom replaces this pattern
+	pattern := regexp.MustCompile(`^(\w+)__(\w+)__((?s).*)$`)
+	atoms := []string{
+		"a__eq__1", "a__b__c__d", "a___b__c", "a__b___c", "__a__b", "a____b",
+		"a__b__", "a__b", "a_b__c_d__e|f", "a__b__c\nd", "é__eq__1", "a-b__eq__1",
+		"a__sum__gt__1__x", "",
+	}
+	for _, atom := range atoms {
+		var exp []string
+		if m := pattern.FindStringSubmatch(atom); m != nil {
+			exp = m[1:]
+		}
+		var got []string
+		if field, op, value, ok := matchAtom(atom); ok {
+			got = []string{field, op, value}
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Fatalf("%q exp: %v, got: %v", atom, exp, got)
+		}
+	}
+}
+
+func TestIsColumn(t *testing.T) {
+	cases := map[string]bool{
+		"age":         true,
+		"users.id":    true,
+		"_a.b_2.c":    true,
+		"":            false,
+		"2a":          false,
+		"users.":      false,
+		".id":         false,
+		"LOWER(name)": false,
+		"a b":         false,
+	}
+	for column, exp := range cases {
+		if got := isColumn(column); got != exp {
+			t.Fatalf("%q exp: %v, got: %v", column, exp, got)
+		}
+	}
+}
+
+func TestSplitItems(t *testing.T) {
+	type item struct {
+		pos  int
+		text string
+	}
+	var got []item
+	splitItems("a,,bc,d", func(i, pos int, text string) bool {
+		got = append(got, item{pos, text})
+		return i < 2
+	})
+	exp := []item{{0, "a"}, {2, ""}, {3, "bc"}}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, got)
+	}
+}
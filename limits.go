@@ -0,0 +1,83 @@
+package djolar
+
+// Limits bound the size of the queries accepted by the parser, so a single
+// URL cannot generate a pathological query. Zero means no limit.
+//
+// Parts of the query over a limit are rejected with ReasonLimitExceeded,
+// and ParseQuery and ParseURI fail even when the parser is not strict,
+// check with errors.Is(err, ErrLimitExceeded). Parse fails closed instead,
+// a filter with a part over a limit is replaced with FalseCondition, so
+// the query matches no rows rather than more rows.
+type Limits struct {
+	// conditions (atoms) of a q or h parameter, over it the whole
+	// parameter is rejected
+	MaxConditions int
+
+	// nesting of groups and negations of a q or h parameter, over it the
+	// whole parameter is rejected
+	MaxDepth int
+
+	// items of a list value, eg., in
+	MaxListSize int
+
+	// bytes of a value, or of the cursor
+	MaxValueLength int
+
+	// entries of the s, g and f parameters, the entries after the limit
+	// are rejected
+	MaxSortKeys      int
+	MaxGroupKeys     int
+	MaxSelectColumns int
+}
+
+// DefaultLimits limits suitable for most APIs, eg.,
+//
+//	parser.Limits = djolar.DefaultLimits
+var DefaultLimits = Limits{
+	MaxConditions:    50,
+	MaxDepth:         10,
+	MaxListSize:      500,
+	MaxValueLength:   1024,
+	MaxSortKeys:      5,
+	MaxGroupKeys:     5,
+	MaxSelectColumns: 50,
+}
+
+// FalseCondition never true condition replacing the filters which cannot be
+// applied as a whole, eg., a q parameter over the limits
+const FalseCondition = "1 = 0"
+
+// exceeds check if n is over the max, 0 means no limit
+func exceeds(max, n int) bool {
+	return max > 0 && n > max
+}
+
+// clauseMark state of a clause before rendering a filter parameter, see
+// failClosed
+type clauseMark struct {
+	args, keys, argc, errs int
+}
+
+func (ctx *parseContext) mark(clause *WhereClause) clauseMark {
+	return clauseMark{len(clause.Arguments), len(ctx.keys), ctx.argc, len(ctx.errs)}
+}
+
+// failClosed check the errors of the filter parameter rendered since the
+// mark, on a fatal error the arguments of the parameter are dropped and
+// it is replaced with FalseCondition, dropping the rejected parts would
+// widen the query
+func (ctx *parseContext) failClosed(m clauseMark, clause *WhereClause, where string) (string, bool) {
+	for _, err := range ctx.errs[m.errs:] {
+		if ctx.fatal(err) {
+			clause.Arguments = clause.Arguments[:m.args]
+			for key, n := range ctx.keys {
+				if n > m.keys {
+					delete(clause.ArgumentMap, key)
+				}
+			}
+			ctx.argc = m.argc
+			return FalseCondition, true
+		}
+	}
+	return where, false
+}
@@ -0,0 +1,161 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLimits(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "age", "n": "name", "s": "score"},
+	}
+	p.Limits = Limits{
+		MaxConditions:    3,
+		MaxDepth:         2,
+		MaxListSize:      3,
+		MaxValueLength:   10,
+		MaxSortKeys:      2,
+		MaxGroupKeys:     1,
+		MaxSelectColumns: 2,
+	}
+
+	cases := []struct {
+		query url.Values
+		exp   []*AtomError
+	}{
+		{url.Values{"q": {"a__eq__1|a__eq__2|a__eq__3|a__eq__4"}},
+			[]*AtomError{{Param: "q", Atom: "a__eq__1|a__eq__2|a__eq__3|a__eq__4", Reason: ReasonLimitExceeded}}},
+		{url.Values{"q": {"!(!(!a__eq__1))"}},
+			[]*AtomError{{Param: "q", Atom: "!(!(!a__eq__1))", Reason: ReasonLimitExceeded}}},
+		{url.Values{"q": {"a__in__[1,2,3,4]"}},
+			[]*AtomError{{Param: "q", Atom: "a__in__[1,2,3,4]", Field: "a", Operator: "in", Reason: ReasonLimitExceeded}}},
+		{url.Values{"q": {"n__eq__0123456789a"}},
+			[]*AtomError{{Param: "q", Atom: "n__eq__0123456789a", Field: "n", Operator: "eq", Reason: ReasonLimitExceeded}}},
+		{url.Values{"s": {"a,-n,s"}},
+			[]*AtomError{{Param: "s", Position: 5, Atom: "s", Reason: ReasonLimitExceeded}}},
+		{url.Values{"g": {"a,n"}},
+			[]*AtomError{{Param: "g", Position: 2, Atom: "n", Reason: ReasonLimitExceeded}}},
+		{url.Values{"f": {"a,n,s,a__sum"}},
+			[]*AtomError{{Param: "f", Position: 4, Atom: "s,a__sum", Reason: ReasonLimitExceeded}}},
+		{url.Values{"h": {"a__sum__gt__1|a__sum__gt__2|a__sum__gt__3|a__sum__gt__4"}},
+			[]*AtomError{{Param: "h", Atom: "a__sum__gt__1|a__sum__gt__2|a__sum__gt__3|a__sum__gt__4", Reason: ReasonLimitExceeded}}},
+	}
+	for _, c := range cases {
+		_, err := p.ParseStrict(c.query)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("exp: *ParseError, got: %v", err)
+		}
+		if !reflect.DeepEqual(perr.Errors, c.exp) {
+			t.Fatalf("exp: %v, got: %v", c.exp, perr.Errors)
+		}
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("exp: ErrLimitExceeded, got: %v", err)
+		}
+	}
+}
+
+func TestParseWithinLimits(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "age", "n": "name", "s": "score"},
+	}
+	p.Limits = Limits{
+		MaxConditions:    3,
+		MaxDepth:         2,
+		MaxListSize:      3,
+		MaxValueLength:   10,
+		MaxSortKeys:      2,
+		MaxGroupKeys:     1,
+		MaxSelectColumns: 2,
+	}
+
+	res, err := p.ParseQuery("q=(a__eq__1||a__in__[1,2,3])|!n__eq__0123456789&s=a,-n&g=a&f=a,n")
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	exp := "(age = ? OR age IN (?)) AND NOT (name = ?)"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+}
+
+func TestParseLimitsNotStrict(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "age", "n": "name", "s": "score"},
+	}
+	p.Limits = Limits{
+		MaxConditions:    3,
+		MaxDepth:         2,
+		MaxListSize:      3,
+		MaxValueLength:   10,
+		MaxSortKeys:      2,
+		MaxGroupKeys:     1,
+		MaxSelectColumns: 2,
+	}
+
+	// limits fail the parse even if not strict
+	_, err := p.ParseQuery("q=a__eq__1|a__eq__2|a__eq__3|a__eq__4|x__eq__1")
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("exp: ErrLimitExceeded, got: %v", err)
+	}
+
+	// other errors still do not
+	if _, err := p.ParseQuery("q=x__eq__1"); err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	if _, err := p.ParseStrict(url.Values{"q": {"x__eq__1"}}); errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("exp: not ErrLimitExceeded, got: %v", err)
+	}
+
+	// Parse drops the sort keys over the limits, and fails closed for the
+	// filters
+	res := p.Parse(url.Values{"q": {"a__eq__" + strings.Repeat("x", 20) + "|n__eq__x"}, "s": {"a,n,s"}})
+	if res.WhereClause.Where != FalseCondition || len(res.WhereClause.Arguments) != 0 || len(res.WhereClause.ArgumentMap) != 0 {
+		t.Fatalf("exp: %v, got: %v %v", FalseCondition, res.WhereClause.Where, res.WhereClause.Arguments)
+	}
+	if res.OrderByClause != "age ASC,name ASC" {
+		t.Fatalf("exp: %v, got: %v", "age ASC,name ASC", res.OrderByClause)
+	}
+}
+
+func TestParseLimitsFailClosed(t *testing.T) {
+	p := NewParser()
+	p.Brackets = true
+	p.Dialect = Postgres
+	p.Metadata = MetaData{
+		QueryMapping:    map[string]string{"a": "age", "n": "name"},
+		ForceConditions: []Condition{{Where: "tenant_id = ?", Args: []interface{}{7}}},
+	}
+	p.Limits = Limits{MaxConditions: 3, MaxListSize: 3}
+
+	cases := []struct {
+		query  url.Values
+		where  string
+		args   []interface{}
+		having string
+	}{
+		{url.Values{"q": {"a__eq__1|a__eq__2|a__eq__3|a__eq__4"}}, "tenant_id = $1 AND 1 = 0", []interface{}{7}, ""},
+		{url.Values{"q": {"n__eq__x||a__in__[1,2,3,4]"}}, "tenant_id = $1 AND 1 = 0", []interface{}{7}, ""},
+		{url.Values{"q": {"!a__in__[1,2,3,4]"}, "n[eq]": {"x"}}, `tenant_id = $1 AND 1 = 0 AND "name" = $2`, []interface{}{7, "x"}, ""},
+		{url.Values{"a[in]": {"1,2,3,4"}, "q": {"n__eq__x"}}, `tenant_id = $1 AND "name" = $2 AND 1 = 0`, []interface{}{7, "x"}, ""},
+		{url.Values{"q": {"n__eq__x"}, "h": {"a__sum__gt__1|a__sum__gt__2|a__sum__gt__3|a__sum__gt__4"}}, `tenant_id = $1 AND "name" = $2`, []interface{}{7, "x"}, "1 = 0"},
+	}
+	for _, c := range cases {
+		res := p.Parse(c.query)
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("%v exp: %v, got: %v", c.query, c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("%v exp: %v, got: %v", c.query, c.args, res.WhereClause.Arguments)
+		}
+		if res.HavingClause.Where != c.having {
+			t.Fatalf("%v exp: %v, got: %v", c.query, c.having, res.HavingClause.Where)
+		}
+	}
+}
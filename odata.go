@@ -0,0 +1,418 @@
+package djolar
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OData support, enabled with Parser.OData. The system query options below
+// are read in addition to the djolar parameters, eg.,
+//
+//	$filter=name eq 'enix' and (age gt 18 or contains(status,'a'))
+//	=> name = ? AND (age > ? OR status LIKE ? ESCAPE '!')
+//	$orderby=age desc,name&$select=name,age&$top=20&$skip=40
+//
+// $filter supports eq, ne, gt, ge, lt, le, in, and, or, not, parentheses,
+// and the functions contains, startswith and endswith. Other functions and
+// the options changing the shape of the result, eg., $expand, are rejected
+// as unsupported: ParseQuery and ParseURI fail even when the parser is not
+// strict, and Parse fails closed with FalseCondition. A $filter which is
+// not well formed fails in the same way, eg., a gt and t eq 5, the parser
+// stops at the error. Strings are quoted, unquoted values are numbers,
+// booleans, null and dates.
+const (
+	ODataFilter  = "$filter"
+	ODataOrderBy = "$orderby"
+	ODataSelect  = "$select"
+	ODataTop     = "$top"
+	ODataSkip    = "$skip"
+)
+
+// odataUnsupported options which cannot be ignored without changing the
+// result
+var odataUnsupported = []string{"$expand", "$apply", "$search", "$compute"}
+
+// odataOperators OData comparison operators mapped to djolar operators
+var odataOperators = map[string]string{
+	"eq": "eq",
+	"ne": "ne",
+	"gt": "gt",
+	"ge": "gte",
+	"lt": "lt",
+	"le": "lte",
+	"in": "in",
+}
+
+// odataFunctions OData boolean functions mapped to djolar operators
+var odataFunctions = map[string]string{
+	"contains":   "co",
+	"startswith": "sw",
+	"endswith":   "ew",
+}
+
+type odataTokenKind int
+
+const (
+	odataEOF odataTokenKind = iota
+	odataWord
+	odataString
+	odataOpen
+	odataClose
+	odataComma
+)
+
+type odataToken struct {
+	kind odataTokenKind
+	text string
+	pos  int
+}
+
+type odataParser struct {
+	src string
+	tok odataToken
+	// end of the current token
+	next int
+	// first error, the parser stops there
+	malformed []*exprNode
+}
+
+// parseOData parse a $filter expression into the expression tree of the
+// atoms, whose parts are the djolar field, operator and value
+func parseOData(src string) (*exprNode, []*exprNode) {
+	p := &odataParser{src: src}
+	p.advance()
+	if p.tok.kind == odataEOF {
+		return &exprNode{kind: exprAtom}, nil
+	}
+	node := p.parseOr()
+	if node == nil {
+		node = &exprNode{kind: exprAtom}
+	}
+	if p.tok.kind != odataEOF {
+		p.fail(p.tok.pos, ReasonMalformed, "")
+	}
+	return node, p.malformed
+}
+
+func (p *odataParser) parseOr() *exprNode {
+	return p.parseList(exprOr, "or", p.parseAnd)
+}
+
+func (p *odataParser) parseAnd() *exprNode {
+	return p.parseList(exprAnd, "and", p.parseUnary)
+}
+
+// parseList parse operands separated by the keyword, a single operand is
+// returned as it is
+func (p *odataParser) parseList(kind exprKind, keyword string, parse func() *exprNode) *exprNode {
+	first := parse()
+	if first == nil {
+		return nil
+	}
+	node := &exprNode{kind: kind, children: []*exprNode{first}}
+	for p.keyword(keyword) {
+		p.advance()
+		child := parse()
+		if child == nil {
+			break
+		}
+		node.children = append(node.children, child)
+	}
+	if len(node.children) == 1 {
+		return first
+	}
+	return node
+}
+
+func (p *odataParser) parseUnary() *exprNode {
+	if p.keyword("not") {
+		p.advance()
+		child := p.parseUnary()
+		if child == nil {
+			return nil
+		}
+		return &exprNode{kind: exprNot, children: []*exprNode{child}}
+	}
+	if p.tok.kind == odataOpen {
+		p.advance()
+		node := p.parseOr()
+		if node == nil {
+			return nil
+		}
+		if p.tok.kind != odataClose {
+			p.fail(p.tok.pos, ReasonMalformed, "")
+			return nil
+		}
+		p.advance()
+		return node
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parse a comparison, eg., age gt 18, or a function call,
+// eg., contains(name,'x')
+func (p *odataParser) parsePrimary() *exprNode {
+	start := p.tok.pos
+	if p.tok.kind != odataWord {
+		p.fail(start, ReasonMalformed, "")
+		return nil
+	}
+	name := p.tok.text
+	p.advance()
+
+	if p.tok.kind == odataOpen {
+		op, ok := odataFunctions[strings.ToLower(name)]
+		if !ok {
+			p.fail(start, ReasonUnsupported, name)
+			return nil
+		}
+		p.advance()
+		member, ok := p.member()
+		if !ok || p.tok.kind != odataComma {
+			p.fail(start, ReasonMalformed, "")
+			return nil
+		}
+		p.advance()
+		value, ok := p.literal()
+		if !ok || p.tok.kind != odataClose {
+			p.fail(start, ReasonMalformed, "")
+			return nil
+		}
+		p.advance()
+		return p.atom(start, member, op, value)
+	}
+
+	if !isMember(name) || p.tok.kind != odataWord {
+		p.fail(start, ReasonMalformed, "")
+		return nil
+	}
+	op, ok := odataOperators[strings.ToLower(p.tok.text)]
+	if !ok {
+		p.fail(start, ReasonUnsupported, p.tok.text)
+		return nil
+	}
+	p.advance()
+	if op != "in" {
+		value, ok := p.literal()
+		if !ok {
+			p.fail(start, ReasonMalformed, "")
+			return nil
+		}
+		return p.atom(start, name, op, value)
+	}
+
+	// status in ('a','b')
+	if p.tok.kind != odataOpen {
+		p.fail(start, ReasonMalformed, "")
+		return nil
+	}
+	p.advance()
+	var items []string
+	for {
+		value, ok := p.literal()
+		if !ok {
+			p.fail(start, ReasonMalformed, "")
+			return nil
+		}
+		items = append(items, value)
+		if p.tok.kind == odataClose {
+			p.advance()
+			break
+		}
+		if p.tok.kind != odataComma {
+			p.fail(start, ReasonMalformed, "")
+			return nil
+		}
+		p.advance()
+	}
+	return p.atom(start, name, op, "["+strings.Join(items, ",")+"]")
+}
+
+func (p *odataParser) atom(start int, field, op, value string) *exprNode {
+	end := p.tok.pos
+	if p.tok.kind == odataEOF {
+		end = len(p.src)
+	}
+	return &exprNode{
+		kind:  exprAtom,
+		atom:  strings.TrimSpace(p.src[start:end]),
+		pos:   start,
+		parts: &atomParts{field: field, op: op, value: value},
+	}
+}
+
+// member read a property name
+func (p *odataParser) member() (string, bool) {
+	if p.tok.kind != odataWord || !isMember(p.tok.text) {
+		return "", false
+	}
+	name := p.tok.text
+	p.advance()
+	return name, true
+}
+
+// literal read a literal, written with the quoting of the atoms. Unquoted
+// literals are numbers, booleans, null and dates, eg., 2024-01-31
+func (p *odataParser) literal() (string, bool) {
+	tok := p.tok
+	switch tok.kind {
+	case odataString:
+		p.advance()
+		return `"` + escapeValue(tok.text) + `"`, true
+	case odataWord:
+		if !isODataLiteral(tok.text) {
+			return "", false
+		}
+		p.advance()
+		if tok.text == "null" {
+			return NullLiteral, true
+		}
+		return `"` + escapeValue(tok.text) + `"`, true
+	}
+	return "", false
+}
+
+// isODataLiteral check if an unquoted word is a literal, other words, eg.,
+// and, are not values
+func isODataLiteral(s string) bool {
+	switch s {
+	case "true", "false", "null":
+		return true
+	}
+	// numbers, dates and times, eg., -1.5e3, 2024-01-31T10:00:00Z
+	digits := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits = true
+		case i == 0 && c != '-' && c != '+' && c != '.':
+			return false
+		case strings.IndexByte("-+.:eETZ", c) < 0:
+			return false
+		}
+	}
+	return digits
+}
+
+func (p *odataParser) keyword(name string) bool {
+	return p.tok.kind == odataWord && strings.EqualFold(p.tok.text, name)
+}
+
+// advance read the next token
+func (p *odataParser) advance() {
+	i := p.next
+	for i < len(p.src) && isSpace(p.src[i]) {
+		i++
+	}
+	p.tok = odataToken{pos: i}
+	if i >= len(p.src) {
+		p.next = i
+		return
+	}
+	switch c := p.src[i]; c {
+	case '(':
+		p.tok.kind, p.next = odataOpen, i+1
+	case ')':
+		p.tok.kind, p.next = odataClose, i+1
+	case ',':
+		p.tok.kind, p.next = odataComma, i+1
+	case '\'':
+		// quotes are escaped by doubling them, eg., 'O''Neil'
+		var b strings.Builder
+		for j := i + 1; j < len(p.src); j++ {
+			if p.src[j] != '\'' {
+				b.WriteByte(p.src[j])
+				continue
+			}
+			if j+1 < len(p.src) && p.src[j+1] == '\'' {
+				b.WriteByte('\'')
+				j++
+				continue
+			}
+			p.tok.kind, p.tok.text, p.next = odataString, b.String(), j+1
+			return
+		}
+		// unterminated string
+		p.tok.kind, p.tok.text, p.next = odataWord, p.src[i:], len(p.src)
+	default:
+		j := i
+		for j < len(p.src) && !isSpace(p.src[j]) && strings.IndexByte("(),'", p.src[j]) < 0 {
+			j++
+		}
+		p.tok.kind, p.tok.text, p.next = odataWord, p.src[i:j], j
+	}
+}
+
+// fail record the first error, the rest of the expression is dropped, so
+// the error is fatal
+func (p *odataParser) fail(pos int, reason Reason, name string) {
+	if p.malformed != nil {
+		return
+	}
+	parts := &atomParts{op: name, reason: reason, stop: true}
+	p.malformed = append(p.malformed, &exprNode{kind: exprAtom, atom: p.src[pos:], pos: pos, parts: parts})
+	p.tok = odataToken{kind: odataEOF, pos: len(p.src)}
+	p.next = len(p.src)
+}
+
+// isMember check if the word is a property path, eg., name or address/city
+func isMember(s string) bool {
+	for _, part := range strings.Split(s, "/") {
+		if !isIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// buildODataOrderby build the sort keys of $orderby, eg., age desc,name
+func (p *Parser) buildODataOrderby(param, value string, orderby []string, ctx *parseContext) []string {
+	splitItems(value, func(i, pos int, item string) bool {
+		if exceeds(p.Limits.MaxSortKeys, i+1) {
+			ctx.reject(param, pos, &AtomError{Atom: value[pos:], Reason: ReasonLimitExceeded})
+			return false
+		}
+		words := strings.Fields(item)
+		if len(words) == 0 {
+			return true
+		}
+		desc := false
+		if len(words) == 2 && (strings.EqualFold(words[1], "desc") || strings.EqualFold(words[1], "asc")) {
+			desc = strings.EqualFold(words[1], "desc")
+		} else if len(words) != 1 {
+			ctx.reject(param, pos, &AtomError{Atom: item, Reason: ReasonMalformed})
+			return true
+		}
+		if column, ok := p.orderColumn(param, pos, item, words[0], ctx); ok {
+			orderby = append(orderby, column+direction(desc))
+		}
+		return true
+	})
+	return orderby
+}
+
+// odataParam the name and the value of an OData option, if OData is enabled
+func (p *Parser) odataParam(query url.Values, name string, ctx *parseContext) (string, string, bool) {
+	if !p.OData {
+		return "", "", false
+	}
+	param := ctx.names.Prefix + name
+	values, ok := query[param]
+	if !ok || len(values) == 0 || len(values[0]) == 0 {
+		return param, "", false
+	}
+	return param, values[0], true
+}
+
+// rejectODataOptions reject the options which are not supported, and
+// report whether any was found
+func (p *Parser) rejectODataOptions(query url.Values, ctx *parseContext) bool {
+	rejected := false
+	for _, name := range odataUnsupported {
+		if param := ctx.names.Prefix + name; len(query[param]) > 0 {
+			ctx.reject(param, 0, &AtomError{Atom: query[param][0], Operator: name, Reason: ReasonUnsupported})
+			rejected = true
+		}
+	}
+	return rejected
+}
@@ -0,0 +1,198 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestODataFilter(t *testing.T) {
+	p := NewParser()
+	p.OData = true
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{
+			"name":    "name",
+			"age":     "age",
+			"status":  "status",
+			"active":  "active",
+			"deleted": "deleted_at",
+		},
+		FieldTypes: map[string]FieldType{"age": TypeInt, "active": TypeBool},
+	}
+
+	cases := []struct {
+		filter string
+		where  string
+		args   []interface{}
+	}{
+		{"name eq 'enix' and age gt 18", "name = ? AND age > ?", []interface{}{"enix", int64(18)}},
+		{"name eq 'enix' and (age ge 18 or status in ('a','b'))", "name = ? AND (age >= ? OR status IN (?))", []interface{}{"enix", int64(18), []string{"a", "b"}}},
+		{"age lt 9 or age le 1 and not (name ne 'x')", "age < ? OR age <= ? AND NOT (name <> ?)", []interface{}{int64(9), int64(1), "x"}},
+		{"contains(name,'a_b') and startswith(status, 'o') and endswith(name,'z')", "name LIKE ? ESCAPE '!' AND status LIKE ? ESCAPE '!' AND name LIKE ? ESCAPE '!'", []interface{}{"%a!_b%", "o%", "%z"}},
+		{"name eq 'O''Neil, \"Jr\"' and active eq true", "name = ? AND active = ?", []interface{}{`O'Neil, "Jr"`, true}},
+		{"deleted eq null and name ne null", "deleted_at IS NULL AND name IS NOT NULL", []interface{}{}},
+		{"name eq 'null'", "name = ?", []interface{}{"null"}},
+		{"Name EQ 'x' AND age GT 1", "age > ?", []interface{}{int64(1)}},
+	}
+	for _, c := range cases {
+		res, _ := p.ParseQuery("$filter=" + url.QueryEscape(c.filter))
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("%s exp: %v, got: %v", c.filter, c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("%s exp: %v, got: %v", c.filter, c.args, res.WhereClause.Arguments)
+		}
+	}
+
+	// joined with the other filters
+	res, _ := p.ParseQuery("q=age__gt__1&$filter=" + url.QueryEscape("name eq 'a' or name eq 'b'"))
+	exp := "age > ? AND (name = ? OR name = ?)"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+
+	// disabled by default
+	p.OData = false
+	if res, _ := p.ParseQuery("$filter=age+gt+1"); res.WhereClause.Where != "" {
+		t.Fatalf("exp: empty where, got: %v", res.WhereClause.Where)
+	}
+}
+
+func TestODataOptions(t *testing.T) {
+	p := NewParser()
+	p.OData = true
+	p.Metadata = MetaData{
+		QueryMapping:   map[string]string{"name": "name", "age": "age"},
+		FieldTypes:     map[string]FieldType{"age": TypeInt},
+		DefaultOrderBy: []string{"id ASC"},
+	}
+
+	exp, _ := p.ParseQuery("q=age__gte__18&s=-age,name&f=name,age&limit=20&offset=40")
+	res, _ := p.ParseQuery("$filter=age+ge+18&$orderby=age+desc,+name+asc&$select=name,+age&$top=20&$skip=40")
+	if !reflect.DeepEqual(res, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, res)
+	}
+
+	// the djolar parameters take precedence for the page window
+	res, _ = p.ParseQuery("$top=20&limit=5&$skip=10")
+	if res.Limit != 5 || res.Offset != 10 || res.OrderByClause != "id ASC" {
+		t.Fatalf("exp: 5 10 id ASC, got: %v %v %v", res.Limit, res.Offset, res.OrderByClause)
+	}
+}
+
+func TestODataErrors(t *testing.T) {
+	p := NewParser()
+	p.OData = true
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{
+			"name":    "name",
+			"age":     "age",
+			"status":  "status",
+			"active":  "active",
+			"deleted": "deleted_at",
+		},
+		FieldTypes: map[string]FieldType{"age": TypeInt, "active": TypeBool},
+	}
+
+	cases := []struct {
+		query string
+		err   *AtomError
+	}{
+		{"$filter=length(name) gt 1", &AtomError{Param: "$filter", Atom: "length(name) gt 1", Operator: "length", Reason: ReasonUnsupported}},
+		{"$filter=age gt 1 and name has 'x'", &AtomError{Param: "$filter", Position: 13, Atom: "name has 'x'", Operator: "has", Reason: ReasonUnsupported}},
+		{"$filter=age gt", &AtomError{Param: "$filter", Atom: "age gt", Reason: ReasonMalformed}},
+		{"$filter=(age gt 1", &AtomError{Param: "$filter", Position: 9, Reason: ReasonMalformed}},
+		{"$filter=contains(name)", &AtomError{Param: "$filter", Atom: "contains(name)", Reason: ReasonMalformed}},
+		{"$filter=age gt 1)", &AtomError{Param: "$filter", Position: 8, Atom: ")", Reason: ReasonMalformed}},
+		{"$filter=tags/any(t: t eq 'x')", &AtomError{Param: "$filter", Atom: "tags/any(t: t eq 'x')", Operator: "tags/any", Reason: ReasonUnsupported}},
+		{"$filter=address/city eq 'x'", &AtomError{Param: "$filter", Atom: "address/city eq 'x'", Field: "address/city", Operator: "eq", Reason: ReasonUnknownField}},
+		{"$orderby=age sideways", &AtomError{Param: "$orderby", Atom: "age sideways", Reason: ReasonMalformed}},
+		{"$select=name,x", &AtomError{Param: "$select", Position: 5, Atom: "x", Field: "x", Reason: ReasonUnknownField}},
+		{"$expand=orders", &AtomError{Param: "$expand", Atom: "orders", Operator: "$expand", Reason: ReasonUnsupported}},
+	}
+	for _, c := range cases {
+		qv, _ := url.ParseQuery(c.query)
+		_, err := p.ParseStrict(qv)
+		var perr *ParseError
+		if !errors.As(err, &perr) || len(perr.Errors) != 1 || !reflect.DeepEqual(perr.Errors[0], c.err) {
+			t.Fatalf("%s exp: %v, got: %v", c.query, c.err, err)
+		}
+	}
+
+	qv, _ := url.ParseQuery("$filter=length(name) gt 1")
+	_, err := p.ParseStrict(qv)
+	exp := `djolar: invalid query: $filter[0]: "length" not supported`
+	if err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, err)
+	}
+}
+
+func TestODataUnsupportedNotStrict(t *testing.T) {
+	p := NewParser()
+	p.OData = true
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"name": "name", "age": "age", "tenant": "tenant_id"},
+	}
+
+	for _, query := range []string{
+		"$filter=" + url.QueryEscape("tolower(name) eq 'x' and tenant eq 5"),
+		"$filter=" + url.QueryEscape("tenant eq 5") + "&$expand=orders",
+		"$filter=" + url.QueryEscape("tenant eq 5") + "&$apply=" + url.QueryEscape("filter(age gt 1)"),
+	} {
+		// unsupported parts fail the parse even if not strict
+		_, err := p.ParseQuery(query)
+		var perr *ParseError
+		if !errors.As(err, &perr) || len(perr.Errors) != 1 || perr.Errors[0].Reason != ReasonUnsupported {
+			t.Fatalf("%s exp: unsupported, got: %v", query, err)
+		}
+
+		// and Parse matches no rows
+		qv, _ := url.ParseQuery(query)
+		res := p.Parse(qv)
+		if !strings.Contains(res.WhereClause.Where, FalseCondition) {
+			t.Fatalf("%s exp: %v, got: %v", query, FalseCondition, res.WhereClause.Where)
+		}
+	}
+
+	qv, _ := url.ParseQuery("$filter=" + url.QueryEscape("tolower(name) eq 'x' and tenant eq 5"))
+	if res := p.Parse(qv); res.WhereClause.Where != FalseCondition || len(res.WhereClause.Arguments) != 0 {
+		t.Fatalf("exp: %v, got: %v %v", FalseCondition, res.WhereClause.Where, res.WhereClause.Arguments)
+	}
+}
+
+func TestODataMalformedFailsClosed(t *testing.T) {
+	p := NewParser()
+	p.OData = true
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"n": "name", "a": "age", "t": "tenant_id", "c": "created_at"},
+		FieldTypes:   map[string]FieldType{"a": TypeInt, "c": TypeTime},
+	}
+
+	// the parser stops at a malformed part, the rest of $filter is lost so
+	// the parse fails even if not strict, and Parse matches no rows
+	for _, filter := range []string{
+		"a gt and t eq 5",
+		"(n eq 'x') t eq 5",
+		"n eq enix and t eq 5",
+		"t eq 5 and a gt 1x",
+	} {
+		_, err := p.ParseQuery("$filter=" + url.QueryEscape(filter))
+		var perr *ParseError
+		if !errors.As(err, &perr) || len(perr.Errors) != 1 || perr.Errors[0].Reason != ReasonMalformed {
+			t.Fatalf("%s exp: malformed, got: %v", filter, err)
+		}
+		res := p.Parse(url.Values{"$filter": {filter}, "q": {"a__gt__1"}})
+		if res.WhereClause.Where != "age > ? AND "+FalseCondition || len(res.WhereClause.Arguments) != 1 {
+			t.Fatalf("%s exp: %v, got: %v %v", filter, FalseCondition, res.WhereClause.Where, res.WhereClause.Arguments)
+		}
+	}
+
+	// unquoted literals are numbers, booleans, null and dates
+	res, err := p.ParseQuery("$filter=" + url.QueryEscape("a ge -1 and a lt 65 and c gt 2024-01-31T10:00:00Z and n ne null"))
+	exp := "age >= ? AND age < ? AND created_at > ? AND name IS NOT NULL"
+	if err != nil || res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v, %v", exp, res.WhereClause.Where, err)
+	}
+}
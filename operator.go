@@ -0,0 +1,230 @@
+package djolar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operator define how an atom operator is translated to SQL
+type Operator struct {
+	WhereClauseHandler WhereClauseHandler
+	ArgumentHandler    ArgumentHandler
+
+	// Pattern mark operators whose argument is a LIKE pattern built from
+	// the value, such arguments are never converted to the field type
+	Pattern bool
+
+	// List mark operators whose value is a comma separated list, the value
+	// is given to the ArgumentHandler as written so that quoted items stay
+	// together, see SplitList. Other values are unquoted first.
+	List bool
+
+	// Range mark List operators taking two bounds, eg., bt. They are bound
+	// as two arguments, and WhereClauseHandler gets both placeholders joined
+	// with AND, eg., "? AND ?"
+	Range bool
+
+	// NoArgument mark operators without placeholder, eg., isnull, and
+	// WhereClauseHandler gets an empty placeholder. The value is empty or a
+	// boolean, false negates the condition, eg., d__isnull__false => NOT
+	// (deleted_at IS NULL), other values are rejected
+	NoArgument bool
+
+	// NullHandler build the clause when the value, or an item of a List
+	// value, is NullLiteral. where is the clause built from the other
+	// items, empty if none. The null literal is rejected if nil.
+	NullHandler func(field, where string) string
+}
+
+// builtin operators, copied into every parser created by NewParser
+var operators = map[string]Operator{
+	"ico": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return "LOWER(" + field + ") LIKE " + placeholder + " ESCAPE '!'"
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return "%" + EscapeLike(strings.ToLower(arg)) + "%"
+		},
+		Pattern: true,
+	},
+	"co": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " LIKE " + placeholder + " ESCAPE '!'"
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return "%" + EscapeLike(arg) + "%"
+		},
+		Pattern: true,
+	},
+	"sw": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " LIKE " + placeholder + " ESCAPE '!'"
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return EscapeLike(arg) + "%"
+		},
+		Pattern: true,
+	},
+	"ew": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " LIKE " + placeholder + " ESCAPE '!'"
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return "%" + EscapeLike(arg)
+		},
+		Pattern: true,
+	},
+	"eq": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " = " + placeholder
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+		NullHandler:     isNullHandler,
+	},
+	"ne": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " <> " + placeholder
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+		NullHandler:     notNullHandler,
+	},
+	"lt": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " < " + placeholder
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+	},
+	"gt": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " > " + placeholder
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+	},
+	"lte": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " <= " + placeholder
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+	},
+	"gte": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " >= " + placeholder
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+	},
+	"in": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " IN (" + placeholder + ")"
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return SplitList(arg)
+		},
+		List:        true,
+		NullHandler: isNullHandler,
+	},
+	"ni": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " NOT IN (" + placeholder + ")"
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return SplitList(arg)
+		},
+		List:        true,
+		NullHandler: notNullHandler,
+	},
+	"bt": {
+		WhereClauseHandler: func(field, placeholder string) string {
+			return field + " BETWEEN " + placeholder
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return SplitList(arg)
+		},
+		List:  true,
+		Range: true,
+	},
+	"isnull": {
+		WhereClauseHandler: func(field, _ string) string {
+			return field + " IS NULL"
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+		NoArgument:      true,
+	},
+	"notnull": {
+		WhereClauseHandler: func(field, _ string) string {
+			return field + " IS NOT NULL"
+		},
+		ArgumentHandler: DefaultArgumentHandler,
+		NoArgument:      true,
+	},
+}
+
+// isNullHandler match NULL, or any of the other values
+// eg., a__in__[1,$null] => (a IN (?) OR a IS NULL)
+func isNullHandler(field, where string) string {
+	if len(where) == 0 {
+		return field + " IS NULL"
+	}
+	return "(" + where + " OR " + field + " IS NULL)"
+}
+
+// notNullHandler exclude NULL, and the other values
+// eg., a__ni__[1,$null] => (a NOT IN (?) AND a IS NOT NULL)
+func notNullHandler(field, where string) string {
+	if len(where) == 0 {
+		return field + " IS NOT NULL"
+	}
+	return "(" + where + " AND " + field + " IS NOT NULL)"
+}
+
+// DefaultOperators return a copy of the builtin operators
+func DefaultOperators() map[string]Operator {
+	ops := make(map[string]Operator, len(operators))
+	for name, op := range operators {
+		ops[name] = op
+	}
+	return ops
+}
+
+// RegisterOperator add an operator to the parser, or override the existing
+// one with the same name. The name must be a word without `__`, and
+// WhereClauseHandler is mandatory, an error is returned otherwise. A nil
+// ArgumentHandler pass the value unchanged.
+func (p *Parser) RegisterOperator(name string, op Operator) error {
+	if !isWord(name) || strings.Contains(name, "__") {
+		return fmt.Errorf("djolar: invalid operator name %q", name)
+	}
+	if op.WhereClauseHandler == nil {
+		return fmt.Errorf("djolar: operator %q has no WhereClauseHandler", name)
+	}
+	if op.ArgumentHandler == nil {
+		op.ArgumentHandler = DefaultArgumentHandler
+	}
+	p.ownOperators()
+	p.operators[name] = op
+	return nil
+}
+
+// RemoveOperator remove an operator from the parser, atoms using it will
+// be rejected as unknown operator
+func (p *Parser) RemoveOperator(name string) {
+	p.ownOperators()
+	delete(p.operators, name)
+}
+
+// Operator lookup an operator of the parser by name
+func (p *Parser) Operator(name string) (Operator, bool) {
+	if p.operators == nil {
+		op, ok := operators[name]
+		return op, ok
+	}
+	op, ok := p.operators[name]
+	return op, ok
+}
+
+// ownOperators make sure the parser has its own operator table, parsers
+// created without NewParser share the builtin operators until modified
+func (p *Parser) ownOperators() {
+	if p.operators == nil {
+		p.operators = DefaultOperators()
+	}
+}
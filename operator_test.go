@@ -0,0 +1,199 @@
+package djolar
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterOperator(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"n": "name",
+	}
+	err := p.RegisterOperator("re", Operator{
+		WhereClauseHandler: func(field, placeholder string) string {
+			return fmt.Sprintf("%s ~ %s", field, placeholder)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, _ := p.ParseQuery("q=n__re__^en")
+
+	exp := "name ~ ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"^en"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestOverrideOperator(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"n": "name",
+	}
+	err := p.RegisterOperator("ico", Operator{
+		WhereClauseHandler: func(field, placeholder string) string {
+			return fmt.Sprintf("%s ILIKE %s", field, placeholder)
+		},
+		ArgumentHandler: func(arg string) interface{} {
+			return "%" + arg + "%"
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, _ := p.ParseQuery("q=n__ico__Enix")
+
+	exp := "name ILIKE ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"%Enix%"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestRemoveOperator(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"n": "name",
+	}
+	p.RemoveOperator("co")
+
+	res, _ := p.ParseQuery("q=n__co__x|n__eq__y")
+
+	exp := "name = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	if _, ok := p.Operator("co"); ok {
+		t.Fatalf("exp: co removed, got: found")
+	}
+}
+
+func TestOperatorRegistryIsPerParser(t *testing.T) {
+	p1 := NewParser()
+	p2 := &Parser{}
+	p1.RemoveOperator("eq")
+	err := p2.RegisterOperator("eq", Operator{
+		WhereClauseHandler: func(field, placeholder string) string {
+			return fmt.Sprintf("%s IS NOT DISTINCT FROM %s", field, placeholder)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := p1.Operator("eq"); ok {
+		t.Fatalf("exp: eq removed from p1, got: found")
+	}
+	op, ok := p2.Operator("eq")
+	if !ok || op.WhereClauseHandler("a", "?") != "a IS NOT DISTINCT FROM ?" {
+		t.Fatalf("exp: eq overridden in p2")
+	}
+
+	p3 := NewParser()
+	op, ok = p3.Operator("eq")
+	if !ok || op.WhereClauseHandler("a", "?") != "a = ?" {
+		t.Fatalf("exp: builtin eq in p3")
+	}
+	if _, ok := DefaultOperators()["eq"]; !ok {
+		t.Fatalf("exp: builtin eq in defaults")
+	}
+}
+
+func TestRegisterInvalidOperator(t *testing.T) {
+	for _, name := range []string{"", "a__b", "a-b"} {
+		p := NewParser()
+		err := p.RegisterOperator(name, Operator{
+			WhereClauseHandler: func(field, placeholder string) string { return "" },
+		})
+		if err == nil || !strings.Contains(err.Error(), "invalid operator name") {
+			t.Fatalf("exp: err for %q, got: %v", name, err)
+		}
+		if _, ok := p.Operator(name); ok {
+			t.Fatalf("exp: %q not registered, got: found", name)
+		}
+	}
+
+	err := NewParser().RegisterOperator("x", Operator{})
+	exp := `djolar: operator "x" has no WhereClauseHandler`
+	if err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, err)
+	}
+}
+
+func TestNullOperators(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"n": "name",
+		"a": "age",
+		"d": "deleted_at",
+	}
+	p.Metadata.FieldTypes = map[string]FieldType{"a": TypeInt}
+
+	cases := []struct {
+		q     string
+		where string
+		args  []interface{}
+	}{
+		{"d__isnull__|n__eq__x", "deleted_at IS NULL AND name = ?", []interface{}{"x"}},
+		{"d__notnull__1", "deleted_at IS NOT NULL", []interface{}{}},
+		{"d__isnull__true", "deleted_at IS NULL", []interface{}{}},
+		{"d__isnull__false|n__eq__x", "NOT (deleted_at IS NULL) AND name = ?", []interface{}{"x"}},
+		{`d__notnull__"false"`, "NOT (deleted_at IS NOT NULL)", []interface{}{}},
+		{"n__eq__$null", "name IS NULL", []interface{}{}},
+		{"n__ne__$null", "name IS NOT NULL", []interface{}{}},
+		{`n__eq__"$null"`, "name = ?", []interface{}{"$null"}},
+		{"a__in__[1,$null,2]", "(age IN (?) OR age IS NULL)", []interface{}{[]interface{}{int64(1), int64(2)}}},
+		{"a__ni__[$null,1]", "(age NOT IN (?) AND age IS NOT NULL)", []interface{}{[]interface{}{int64(1)}}},
+		{"a__in__[$null]", "age IS NULL", []interface{}{}},
+		{`n__in__["$null",$null]`, "(name IN (?) OR name IS NULL)", []interface{}{[]string{"$null"}}},
+		{"n__eq__$null|a__gt__1", "name IS NULL AND age > ?", []interface{}{int64(1)}},
+	}
+	for _, c := range cases {
+		res, _ := p.ParseQuery("q=" + url.QueryEscape(c.q))
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("exp: %v, got: %v", c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("exp: %v, got: %v", c.args, res.WhereClause.Arguments)
+		}
+	}
+
+	// arguments stay aligned with numbered placeholders
+	p.Dialect = Postgres
+	res, _ := p.ParseQuery("q=" + url.QueryEscape("d__isnull__|a__in__[1,$null,2]|n__eq__x"))
+	exp := `"deleted_at" IS NULL AND ("age" IN ($1, $2) OR "age" IS NULL) AND "name" = $3`
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	if len(res.WhereClause.ArgumentMap) != 2 {
+		t.Fatalf("exp: %v, got: %v", 2, res.WhereClause.ArgumentMap)
+	}
+
+	// null is not a valid value of other operators
+	p.Dialect = nil
+	_, err := p.ParseStrict(map[string][]string{"q": {"a__gt__$null"}})
+	if perr, ok := err.(*ParseError); !ok || perr.Errors[0].Reason != ReasonInvalidValue {
+		t.Fatalf("exp: invalid value, got: %v", err)
+	}
+
+	// isnull and notnull take a boolean, if any
+	for _, q := range []string{"d__isnull__no", "d__notnull__$null"} {
+		_, err := p.ParseStrict(map[string][]string{"q": {q}})
+		if perr, ok := err.(*ParseError); !ok || perr.Errors[0].Reason != ReasonInvalidValue {
+			t.Fatalf("%s exp: invalid value, got: %v", q, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package djolar
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// buildPagination read the page window from `limit` and `offset`, or from
+// `page` (1-based) and `per_page` when neither limit nor offset is given,
+// see ParamNames, and from `$top` and `$skip` with OData. The limit falls
+// back to DefaultLimit, and both are capped by MaxLimit and MaxOffset.
+// Pages after the first need a page size, per_page or DefaultLimit, and
+// are rejected without.
+func (p *Parser) buildPagination(query url.Values, result *ParseResult, ctx *parseContext) {
+	limit, hasLimit := p.paginationParam(query, ctx.names.Limit, 1, ctx)
+	offset, hasOffset := p.paginationParam(query, ctx.names.Offset, 0, ctx)
+	if p.OData && !hasLimit {
+		limit, hasLimit = p.paginationParam(query, ctx.names.Prefix+ODataTop, 1, ctx)
+	}
+	if p.OData && !hasOffset {
+		offset, hasOffset = p.paginationParam(query, ctx.names.Prefix+ODataSkip, 0, ctx)
+	}
+	var page int64
+	var hasPage bool
+	if !hasLimit && !hasOffset {
+		limit, hasLimit = p.paginationParam(query, ctx.names.PerPage, 1, ctx)
+		page, hasPage = p.paginationParam(query, ctx.names.Page, 1, ctx)
+	}
+
+	if !hasLimit {
+		limit = int64(p.Metadata.DefaultLimit)
+	}
+	if max := int64(p.Metadata.MaxLimit); max > 0 && (limit == 0 || limit > max) {
+		limit = max
+	}
+	if hasPage && limit == 0 && page > 1 {
+		// without page size every page would be the first one
+		ctx.reject(ctx.names.Page, 0, &AtomError{Atom: query[ctx.names.Page][0], Field: ctx.names.Page, Reason: ReasonInvalidValue})
+	} else if hasPage {
+		offset = (page - 1) * limit
+	}
+	if max := int64(p.Metadata.MaxOffset); max > 0 && offset > max {
+		offset = max
+	}
+	if offset > math.MaxInt32 {
+		offset = math.MaxInt32
+	}
+
+	result.Limit = int(limit)
+	result.Offset = int(offset)
+}
+
+// paginationParam read a non negative integer parameter, values below min
+// or not a number are rejected
+func (p *Parser) paginationParam(query url.Values, name string, min int64, ctx *parseContext) (int64, bool) {
+	values, ok := query[name]
+	if !ok || len(values) == 0 || len(values[0]) == 0 {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(values[0], 10, 32)
+	if err != nil || value < min {
+		ctx.reject(name, 0, &AtomError{Atom: values[0], Field: name, Reason: ReasonInvalidValue})
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,96 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	p := NewParser()
+
+	cases := []struct {
+		query  string
+		limit  int
+		offset int
+	}{
+		{"", 0, 0},
+		{"limit=20&offset=40", 20, 40},
+		{"offset=5", 0, 5},
+		{"page=3&per_page=10", 10, 20},
+		{"page=1&per_page=10", 10, 0},
+		{"per_page=15", 15, 0},
+		{"limit=20&page=3&per_page=10", 20, 0},
+		{"limit=abc&offset=-1", 0, 0},
+	}
+	for _, c := range cases {
+		res, _ := p.ParseQuery(c.query)
+		if res.Limit != c.limit || res.Offset != c.offset {
+			t.Fatalf("%s: exp: %d/%d, got: %d/%d", c.query, c.limit, c.offset, res.Limit, res.Offset)
+		}
+	}
+}
+
+func TestParsePaginationCaps(t *testing.T) {
+	p := NewParser()
+	p.Metadata.DefaultLimit = 20
+	p.Metadata.MaxLimit = 100
+	p.Metadata.MaxOffset = 1000
+
+	cases := []struct {
+		query  string
+		limit  int
+		offset int
+	}{
+		{"", 20, 0},
+		{"limit=50", 50, 0},
+		{"limit=500&offset=5000", 100, 1000},
+		{"page=4", 20, 60},
+		{"page=3&per_page=1000", 100, 200},
+		{"page=2147483647&per_page=100", 100, 1000},
+		{"limit=0", 20, 0},
+	}
+	for _, c := range cases {
+		res, _ := p.ParseQuery(c.query)
+		if res.Limit != c.limit || res.Offset != c.offset {
+			t.Fatalf("%s: exp: %d/%d, got: %d/%d", c.query, c.limit, c.offset, res.Limit, res.Offset)
+		}
+	}
+
+	// MaxLimit applies even without default
+	p.Metadata.DefaultLimit = 0
+	res, _ := p.ParseQuery("")
+	if res.Limit != 100 {
+		t.Fatalf("exp: %d, got: %d", 100, res.Limit)
+	}
+}
+
+func TestParseInvalidPagination(t *testing.T) {
+	p := NewParser()
+
+	qv, _ := url.ParseQuery("limit=0&offset=x&page=0")
+	_, err := p.ParseStrict(qv)
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("exp: *ParseError, got: %v", err)
+	}
+	exp := []*AtomError{
+		{Param: "limit", Atom: "0", Field: "limit", Reason: ReasonInvalidValue},
+		{Param: "offset", Atom: "x", Field: "offset", Reason: ReasonInvalidValue},
+		{Param: "page", Atom: "0", Field: "page", Reason: ReasonInvalidValue},
+	}
+	if !reflect.DeepEqual(perr.Errors, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, perr.Errors)
+	}
+
+	// pages after the first need a page size
+	_, err = p.ParseStrict(url.Values{"page": {"3"}})
+	expErr := &AtomError{Param: "page", Atom: "3", Field: "page", Reason: ReasonInvalidValue}
+	if !errors.As(err, &perr) || len(perr.Errors) != 1 || !reflect.DeepEqual(perr.Errors[0], expErr) {
+		t.Fatalf("exp: %v, got: %v", expErr, err)
+	}
+	if _, err := p.ParseStrict(url.Values{"page": {"1"}}); err != nil {
+		t.Fatalf("exp: nil, got: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package djolar
+
+import "fmt"
+
+// ParamNames names of the query parameters read by the parser. Empty names
+// keep the default, eg., q for Query. Prefix is prepended to every name, so
+// that several parsers can read their own parameters from the same query,
+// eg., with prefixes "users." and "orders.":
+//
+//	?users.q=a__gt__18&users.s=-a&orders.q=t__gt__100&orders.limit=5
+type ParamNames struct {
+	Query   string // q
+	Sort    string // s
+	Group   string // g
+	Select  string // f
+	Having  string // h
+	Limit   string // limit
+	Offset  string // offset
+	Page    string // page
+	PerPage string // per_page
+	Cursor  string // cursor
+
+	Prefix string
+}
+
+// DefaultParamNames the parameter names used when ParamNames is empty
+var DefaultParamNames = ParamNames{
+	Query:   "q",
+	Sort:    "s",
+	Group:   "g",
+	Select:  "f",
+	Having:  "h",
+	Limit:   "limit",
+	Offset:  "offset",
+	Page:    "page",
+	PerPage: "per_page",
+	Cursor:  "cursor",
+}
+
+// resolve the full name of every parameter, defaults filled in and prefix
+// prepended. The prefix is kept for the bracket filters.
+func (n ParamNames) resolve() ParamNames {
+	names := n.list()
+	defaults := DefaultParamNames.list()
+	for i, name := range names {
+		if *name == "" {
+			*name = *defaults[i]
+		}
+		*name = n.Prefix + *name
+	}
+	return n
+}
+
+func (n *ParamNames) list() []*string {
+	return []*string{
+		&n.Query, &n.Sort, &n.Group, &n.Select, &n.Having,
+		&n.Limit, &n.Offset, &n.Page, &n.PerPage, &n.Cursor,
+	}
+}
+
+// validate check that no two parameters have the same name
+func (n ParamNames) validate() error {
+	names := n.resolve()
+	seen := make(map[string]bool)
+	for _, name := range names.list() {
+		if seen[*name] {
+			return fmt.Errorf("djolar: parameter %q used twice", *name)
+		}
+		seen[*name] = true
+	}
+	return nil
+}
+
+// paramNames resolved parameter names of the parser
+func (p *Parser) paramNames() ParamNames {
+	if p.params != nil {
+		return *p.params
+	}
+	return p.Params.resolve()
+}
@@ -0,0 +1,60 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParamNames(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{"a": "age", "n": "name"}
+	p.Params = ParamNames{Query: "filter", Sort: "sort", Limit: "size"}
+
+	res, _ := p.ParseQuery("filter=a__gt__1&sort=-a&s=n&q=n__eq__x&size=5&g=n")
+	if res.WhereClause.Where != "age > ?" {
+		t.Fatalf("exp: %v, got: %v", "age > ?", res.WhereClause.Where)
+	}
+	if res.OrderByClause != "age DESC" {
+		t.Fatalf("exp: %v, got: %v", "age DESC", res.OrderByClause)
+	}
+	if res.Limit != 5 || res.GroupByClause != "name" {
+		t.Fatalf("exp: 5 name, got: %v %v", res.Limit, res.GroupByClause)
+	}
+
+	// errors report the parameter as named in the query
+	_, err := p.ParseStrict(url.Values{"filter": {"b__eq__1"}, "size": {"x"}})
+	var perr *ParseError
+	if !errors.As(err, &perr) || len(perr.Errors) != 2 || perr.Errors[0].Param != "filter" || perr.Errors[1].Param != "size" {
+		t.Fatalf("exp: filter and size errors, got: %v", err)
+	}
+}
+
+func TestParamNamesPrefix(t *testing.T) {
+	users := NewParser()
+	users.Metadata.QueryMapping = map[string]string{"a": "age"}
+	users.Params.Prefix = "users."
+	orders := NewParser()
+	orders.Metadata.QueryMapping = map[string]string{"t": "total"}
+	orders.Params = ParamNames{Prefix: "orders.", Limit: "n"}
+
+	qv, _ := url.ParseQuery("users.q=a__gt__18&users.s=-a&orders.q=t__gt__100&orders.n=5&q=a__eq__1")
+	res := users.MustCompile().Parse(qv)
+	exp := []string{"age > ?", "age DESC"}
+	if got := []string{res.WhereClause.Where, res.OrderByClause}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, got)
+	}
+	res = orders.Parse(qv)
+	if res.WhereClause.Where != "total > ?" || res.Limit != 5 {
+		t.Fatalf("exp: total > ? limited to 5, got: %v %v", res.WhereClause.Where, res.Limit)
+	}
+}
+
+func TestParamNamesDistinct(t *testing.T) {
+	p := NewParser()
+	p.Params = ParamNames{Sort: "q"}
+	if _, err := p.Compile(); err == nil || err.Error() != `djolar: parameter "q" used twice` {
+		t.Fatalf("exp: duplicate parameter, got: %v", err)
+	}
+}
@@ -3,8 +3,11 @@ package djolar
 import (
 	"fmt"
 	"net/url"
-	"regexp"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var defaultAggregateFunctions = map[string]string{
@@ -21,107 +24,14 @@ type ArgumentHandler func(value string) interface{}
 // Where clause processor
 type WhereClauseHandler func(field, placeholder string) string
 
-// PlaceHolderFunc get place holder for given field name
+// PlaceHolderFunc get place holder for given field name. The n-th argument
+// of a field in a query is named with a suffix, eg., a, a_2, a_3
 type PlaceHolderFunc func(md *MetaData, fieldname string) string
 
-// ArgMapKeyFunc get argment map key
+// ArgMapKeyFunc get argment map key, the field name is suffixed as for
+// PlaceHolderFunc, keys already in use get the suffix appended
 type ArgMapKeyFunc func(md *MetaData, fieldname string) string
 
-type Operator struct {
-	WhereClauseHandler WhereClauseHandler
-	ArgumentHandler    ArgumentHandler
-}
-
-var (
-	queryPattern = regexp.MustCompile(`(\w+)__(\w+)__(.*)`)
-	operators    = map[string]Operator{
-		"ico": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("LOWER(%s) LIKE %s", field, placeholder)
-			},
-			ArgumentHandler: func(arg string) interface{} {
-				return fmt.Sprintf("%%%s%%", strings.ToLower(arg))
-			},
-		},
-		"co": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s LIKE %s", field, placeholder)
-			},
-			ArgumentHandler: func(arg string) interface{} {
-				return fmt.Sprintf("%%%s%%", arg)
-			},
-		},
-		"sw": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s LIKE %s", field, placeholder)
-			},
-			ArgumentHandler: func(arg string) interface{} {
-				return fmt.Sprintf("%s%%", arg)
-			},
-		},
-		"ew": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s LIKE %s", field, placeholder)
-			},
-			ArgumentHandler: func(arg string) interface{} {
-				return fmt.Sprintf("%%%s", arg)
-			},
-		},
-		"eq": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s = %s", field, placeholder)
-			},
-			ArgumentHandler: DefaultArgumentHandler,
-		},
-		"ne": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s <> %s", field, placeholder)
-			},
-			ArgumentHandler: DefaultArgumentHandler,
-		},
-		"lt": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s < %s", field, placeholder)
-			},
-			ArgumentHandler: DefaultArgumentHandler,
-		},
-		"gt": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s > %s", field, placeholder)
-			},
-			ArgumentHandler: DefaultArgumentHandler,
-		},
-		"lte": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s <= %s", field, placeholder)
-			},
-			ArgumentHandler: DefaultArgumentHandler,
-		},
-		"gte": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s >= %s", field, placeholder)
-			},
-			ArgumentHandler: DefaultArgumentHandler,
-		},
-		"in": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s IN (%s)", field, placeholder)
-			},
-			ArgumentHandler: func(arg string) interface{} {
-				return strings.Split(strings.TrimRight(strings.TrimLeft(arg, "["), "]"), ",")
-			},
-		},
-		"ni": {
-			WhereClauseHandler: func(field, placeholder string) string {
-				return fmt.Sprintf("%s NOT IN (%s)", field, placeholder)
-			},
-			ArgumentHandler: func(arg string) interface{} {
-				return strings.Split(strings.TrimRight(strings.TrimLeft(arg, "["), "]"), ",")
-			},
-		},
-	}
-)
-
 // MetaData meta data for djolar search engine
 type MetaData struct {
 	// used to convert query field to db field
@@ -146,6 +56,19 @@ type MetaData struct {
 	// ForceSearch format is as the same as DefaultSearch
 	ForceSearch map[string]interface{}
 
+	// ordered alternative to DefaultSearch, applied first in the given order,
+	// DefaultSearch entries follow sorted by key
+	// DefaultConditions example:
+	// 		[]Condition{
+	// 			{Where: "age = ?", Args: []interface{}{18}},
+	// 			{Where: "deleted_at IS NULL"},
+	// 		}
+	DefaultConditions []Condition
+
+	// ordered alternative to ForceSearch, applied first in the given order,
+	// ForceSearch entries follow sorted by key
+	ForceConditions []Condition
+
 	// default order by fields
 	// DefaultOrderBy example []string{"age ASC", "name DESC"}
 	DefaultOrderBy []string
@@ -164,6 +87,55 @@ type MetaData struct {
 	//   "avg": "AVG",
 	// }
 	AggregateFunctions map[string]string
+
+	// value type of query fields, arguments are converted to the Go type
+	// of the field, fields not listed are kept as string
+	// FieldTypes example:
+	// 		map[string]FieldType{
+	// 			"a": TypeInt,
+	// 			"c": TypeTime,
+	// 		}
+	FieldTypes map[string]FieldType
+
+	// location of TypeTime values without time zone, UTC if nil
+	TimeLocation *time.Location
+
+	// operators accepted by each query field, aggregates used in the HAVING
+	// clause follow the rules of the aggregated field unless listed on their
+	// own (eg., "a__sum")
+	// AllowedOperators example:
+	// 		map[string][]string{
+	// 			"n": {"eq", "co"},
+	// 			"u": {"eq", "in"},
+	// 		}
+	AllowedOperators map[string][]string
+
+	// operators accepted by query fields not listed in AllowedOperators,
+	// any operator is accepted if nil
+	DefaultAllowedOperators []string
+
+	// query fields accepted by the s parameter, any mapped field if nil
+	SortableFields []string
+
+	// query fields accepted by the g parameter, any mapped field if nil
+	GroupableFields []string
+
+	// query fields which can be aggregated in the f and h parameters,
+	// any mapped field if nil
+	AggregatableFields []string
+
+	// page size applied when the request has no limit, no limit if 0
+	DefaultLimit int
+
+	// maximum page size, larger limits are capped, no cap if 0
+	MaxLimit int
+
+	// maximum offset, larger offsets are capped, no cap if 0
+	MaxOffset int
+
+	// unique column appended to the ORDER BY clause to enable keyset
+	// pagination with the cursor parameter, eg., "id"
+	CursorTiebreaker string
 }
 
 // Parser djolar search engine parser
@@ -171,6 +143,51 @@ type Parser struct {
 	Metadata       MetaData
 	GetPlaceHolder PlaceHolderFunc
 	GetArgMapKey   ArgMapKeyFunc
+
+	// Strict make ParseQuery and ParseURI fail with a *ParseError
+	// instead of dropping invalid atoms, see ParseStrict
+	Strict bool
+
+	// Dialect number placeholders, expand lists and quote columns for the
+	// given database, eg., Postgres. GetPlaceHolder is ignored if set.
+	Dialect Dialect
+
+	// Now clock of relative time values, eg., now-7d, time.Now if nil
+	Now func() time.Time
+
+	// Limits bound the size of accepted queries, see DefaultLimits
+	Limits Limits
+
+	// Params rename the query parameters, eg., to avoid a clash with
+	// parameters of the application, see ParamNames
+	Params ParamNames
+
+	// Brackets accept filters written as field[operator]=value, eg.,
+	// age[gte]=18&status[in]=a&status[in]=b, in addition to q
+	Brackets bool
+
+	// Syntax of the q and h expressions, eg., SyntaxRSQL
+	Syntax Syntax
+
+	// OData read the OData options $filter, $orderby, $select, $top and
+	// $skip in addition to the djolar parameters
+	OData bool
+
+	// operator registry, see RegisterOperator
+	operators map[string]Operator
+
+	// columns and aggregates resolved by Compile, eg., a => "age" and
+	// a__sum => SUM("age")
+	columns    map[string]string
+	aggregates map[string]string
+	// parameter names resolved by Compile
+	params *ParamNames
+}
+
+// Condition raw SQL condition with its arguments
+type Condition struct {
+	Where string
+	Args  []interface{}
 }
 
 // WhereClause where clause
@@ -187,6 +204,16 @@ type ParseResult struct {
 	GroupByClause string
 	HavingClause  *WhereClause
 	OrderByClause string
+
+	// page window, no limit if Limit is 0
+	Limit  int
+	Offset int
+
+	// columns of the keyset cursor, in ORDER BY order, see NextCursor
+	CursorColumns []string
+
+	// dialect of the parser, see Statement
+	dialect Dialect
 }
 
 // NewParser create a new parser
@@ -203,19 +230,25 @@ func NewParser() *Parser {
 		Metadata:       md,
 		GetPlaceHolder: defaultPlaceHolderFunc,
 		GetArgMapKey:   defaultArgMapFunc,
+		operators:      DefaultOperators(),
 	}
 	return p
 }
 
 // ParseQuery parse with query string
 func (p *Parser) ParseQuery(query string) (*ParseResult, error) {
-	qv, err := url.ParseQuery(query)
+	var qv url.Values
+	var err error
+	if p.Syntax == SyntaxRSQL {
+		qv, err = rsqlQuery(query)
+	} else {
+		qv, err = url.ParseQuery(query)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	res := p.Parse(qv)
-	return res, nil
+	return p.parseValues(qv, &parseContext{})
 }
 
 // ParseURI parse given URI string, and extrat djolar compatiable query conditions
@@ -225,13 +258,124 @@ func (p *Parser) ParseURI(uri string) (*ParseResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	var qv url.Values
+	if p.Syntax == SyntaxRSQL {
+		if qv, err = rsqlQuery(u.RawQuery); err != nil {
+			return nil, err
+		}
+	} else {
+		qv = u.Query()
+	}
+
+	return p.parseValues(qv, &parseContext{})
+}
 
-	res := p.Parse(u.Query())
-	return res, nil
+// rsqlQuery decode a query string whose pairs are only split on &, the
+// RSQL syntax uses ; as AND, which url.ParseQuery rejects
+func rsqlQuery(query string) (url.Values, error) {
+	qv := url.Values{}
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value := pair, ""
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			key, value = pair[:i], pair[i+1:]
+		}
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err = url.QueryUnescape(value)
+		if err != nil {
+			return nil, err
+		}
+		qv[key] = append(qv[key], value)
+	}
+	return qv, nil
 }
 
-// Parse parse url query values
+// Parse parse url query values. Invalid atoms are silently dropped,
+// use ParseStrict to get them reported.
+//
+// With SyntaxRSQL, url.ParseQuery and r.URL.Query() drop the pairs holding
+// a raw ;, so the q and h parameters are lost. Use ParseQuery with
+// r.URL.RawQuery, or send ; as %3B.
 func (p *Parser) Parse(query url.Values) *ParseResult {
+	ctx := &parseContext{}
+	return p.parse(query, ctx)
+}
+
+// ParseStrict parse url query values, and return a *ParseError listing
+// every rejected atom if any.
+func (p *Parser) ParseStrict(query url.Values) (*ParseResult, error) {
+	ctx := &parseContext{}
+	result := p.parse(query, ctx)
+	if len(ctx.errs) > 0 {
+		return nil, &ParseError{Errors: ctx.errs}
+	}
+	return result, nil
+}
+
+// parseValues parse, and fail with the rejected atoms if the parser is
+// strict, or with the fatal ones, eg., over the limits
+func (p *Parser) parseValues(query url.Values, ctx *parseContext) (*ParseResult, error) {
+	result := p.parse(query, ctx)
+	errs := ctx.errs
+	if !p.Strict {
+		// queries over the limits, or with unsupported parts, fail even
+		// if not strict
+		errs = nil
+		for _, err := range ctx.errs {
+			if ctx.fatal(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ParseError{Errors: errs}
+	}
+	return result, nil
+}
+
+// parseContext state of a single parse call
+type parseContext struct {
+	errs []*AtomError
+
+	// number of arguments bound with the dialect placeholders
+	argc int
+
+	// ArgumentMap keys in use, numbered from 1 in the order of use, see
+	// argName
+	keys map[string]int
+
+	// time of the request, relative time values are evaluated against it
+	now time.Time
+
+	// names of the query parameters
+	names ParamNames
+
+	// JSON filter document, see ParseDocument
+	doc *FilterDocument
+
+	// errors of the expressions the front end stopped at, see atomParts
+	stopped map[*AtomError]bool
+}
+
+func (ctx *parseContext) reject(param string, pos int, err *AtomError) {
+	err.Param = param
+	err.Position = pos
+	ctx.errs = append(ctx.errs, err)
+}
+
+// fatal check if the error fails the parse even if the parser is not
+// strict, see AtomError.fatal. Errors the front end stopped at are fatal
+// too, the rest of the expression is lost.
+func (ctx *parseContext) fatal(err *AtomError) bool {
+	return err.fatal() || ctx.stopped[err]
+}
+
+func (p *Parser) parse(query url.Values, ctx *parseContext) *ParseResult {
 	args := make([]interface{}, 0)
 	argMap := make(map[string]interface{})
 	where := make([]string, 0)
@@ -239,216 +383,613 @@ func (p *Parser) Parse(query url.Values) *ParseResult {
 	result := &ParseResult{
 		WhereClause:  &WhereClause{},
 		HavingClause: &WhereClause{},
+		dialect:      p.Dialect,
 	}
-
-	if p.GetPlaceHolder == nil {
-		p.GetPlaceHolder = defaultPlaceHolderFunc
+	if p.Now != nil {
+		ctx.now = p.Now()
+	} else {
+		ctx.now = time.Now()
 	}
-	if p.GetArgMapKey == nil {
-		p.GetArgMapKey = defaultArgMapFunc
+	if ctx.doc == nil {
+		ctx.names = p.paramNames()
 	}
 
 	// Apply force search if defined
-	for fieldName, value := range p.Metadata.ForceSearch {
-		where = append(where, fieldName)
-		args = append(args, value)
+	for _, cond := range searchConditions(p.Metadata.ForceConditions, p.Metadata.ForceSearch) {
+		wh, condArgs := p.bindCondition(cond, ctx)
+		where = append(where, wh)
+		args = append(args, condArgs...)
 	}
 
 	// Query
-	if paramQ, ok := query["q"]; ok && len(paramQ) >= 1 && len(paramQ[0]) > 0 {
-		qVal := paramQ[0]
-		for _, field := range strings.Split(qVal, "|") {
-			col, wh, arg, ok := p.buildWhereClause(field, p.Metadata.QueryMapping)
-			if !ok {
-				continue
+	// user expressions with a top level OR
+	var userOr []int
+	hasQuery := false
+	if paramQ, ok := query[ctx.names.Query]; ok && len(paramQ) >= 1 && len(paramQ[0]) > 0 {
+		clause := &WhereClause{Arguments: args, ArgumentMap: argMap}
+		wh, kind, ok := p.renderParam(ctx.names.Query, paramQ[0], p.exprParser(), clause, p.resolveField, ctx)
+		if ok {
+			if kind == exprOr {
+				userOr = append(userOr, len(where))
 			}
 			where = append(where, wh)
-			args = append(args, arg)
-			argMap[p.GetArgMapKey(&p.Metadata, col)] = arg
 		}
-	} else {
+		args = clause.Arguments
+		hasQuery = true
+	}
+
+	// OData filter, eg., $filter=age ge 18
+	if param, value, ok := p.odataParam(query, ODataFilter, ctx); ok {
+		clause := &WhereClause{Arguments: args, ArgumentMap: argMap}
+		wh, kind, ok := p.renderParam(param, value, parseOData, clause, p.resolveField, ctx)
+		if ok {
+			if kind == exprOr {
+				userOr = append(userOr, len(where))
+			}
+			where = append(where, wh)
+		}
+		args = clause.Arguments
+		hasQuery = true
+	}
+
+	// Bracket filters, eg., age[gte]=18
+	if filters := p.bracketFilters(query, ctx); len(filters) > 0 {
+		clause := &WhereClause{Arguments: args, ArgumentMap: argMap}
+		m := ctx.mark(clause)
+		wh, ok := p.buildBrackets(filters, clause, ctx)
+		if wh, failed := ctx.failClosed(m, clause, wh); failed || ok {
+			where = append(where, wh)
+		}
+		args = clause.Arguments
+		hasQuery = true
+	}
+
+	// JSON filter document
+	if ctx.doc != nil && ctx.doc.Filter != nil {
+		clause := &WhereClause{Arguments: args, ArgumentMap: argMap}
+		wh, kind, ok := p.renderDocument(ctx.names.Query, ctx.doc.Filter, clause, p.resolveField, ctx)
+		if ok {
+			if kind == exprOr {
+				userOr = append(userOr, len(where))
+			}
+			where = append(where, wh)
+		}
+		args = clause.Arguments
+		hasQuery = true
+	}
+
+	if !hasQuery {
 		// apply default search if defined
-		for fieldName, value := range p.Metadata.DefaultSearch {
-			where = append(where, fieldName)
-			args = append(args, value)
-			argMap[p.GetArgMapKey(&p.Metadata, fieldName)] = value
+		for _, cond := range searchConditions(p.Metadata.DefaultConditions, p.Metadata.DefaultSearch) {
+			wh, condArgs := p.bindCondition(cond, ctx)
+			where = append(where, wh)
+			args = append(args, condArgs...)
+			if len(cond.Args) == 1 {
+				_, key := p.argName(cond.Where, ctx)
+				argMap[key] = cond.Args[0]
+			}
 		}
 	}
-	result.WhereClause.Where = strings.Join(where, " AND ")
-	result.WhereClause.Arguments = args
-	result.WhereClause.ArgumentMap = argMap
 
 	// Order by
 
 	// Apply force orderby
 	orderby = append(orderby, p.Metadata.ForceOrderBy...)
-	if paramOrderby, ok := query["s"]; ok && len(paramOrderby) >= 1 && len(paramOrderby[0]) > 0 {
+	sorted := false
+	if paramOrderby, ok := query[ctx.names.Sort]; ok && len(paramOrderby) >= 1 && len(paramOrderby[0]) > 0 {
 		// s query param is provided
 		orderbyVal := paramOrderby[0]
-		orderby = p.buildOrderby(orderbyVal, orderby)
-	} else if len(p.Metadata.DefaultOrderBy) != 0 {
+		orderby = p.buildOrderby(paramList{value: orderbyVal}, orderby, ctx)
+		sorted = true
+	}
+	if param, value, ok := p.odataParam(query, ODataOrderBy, ctx); ok {
+		orderby = p.buildODataOrderby(param, value, orderby, ctx)
+		sorted = true
+	}
+	if ctx.doc != nil && len(ctx.doc.Sort) > 0 {
+		orderby = p.buildOrderby(paramList{items: ctx.doc.Sort}, orderby, ctx)
+		sorted = true
+	}
+	if !sorted && len(p.Metadata.DefaultOrderBy) != 0 {
 		// Apply default order by
 		orderby = append(orderby, p.Metadata.DefaultOrderBy...)
 	}
+
+	// Keyset pagination
+	keyset := false
+	if p.Metadata.CursorTiebreaker != "" {
+		var keys []sortKey
+		orderby, keys = p.keysetOrder(orderby)
+		for _, key := range keys {
+			result.CursorColumns = append(result.CursorColumns, key.column)
+		}
+		if paramCursor, ok := query[ctx.names.Cursor]; ok && len(paramCursor) >= 1 && len(paramCursor[0]) > 0 {
+			clause := &WhereClause{Arguments: args, ArgumentMap: argMap}
+			if wh, ok := p.buildCursor(paramCursor[0], keys, clause, ctx); ok {
+				where = append(where, wh)
+				keyset = true
+			}
+			args = clause.Arguments
+		}
+	}
 	result.OrderByClause = strings.Join(orderby, ",")
 
+	// OData options which cannot be applied, eg., $apply, fail closed
+	if p.OData && p.rejectODataOptions(query, ctx) {
+		where = append(where, FalseCondition)
+	}
+
+	for _, i := range userOr {
+		if len(where) == 1 {
+			break
+		}
+		// keep the other criteria out of the user's OR
+		where[i] = "(" + where[i] + ")"
+	}
+	result.WhereClause.Where = strings.Join(where, " AND ")
+	result.WhereClause.Arguments = args
+	result.WhereClause.ArgumentMap = argMap
+
 	// Group by
 	// Ex. g=field1,field2
-	if paramGroupBy, ok := query["g"]; ok && len(paramGroupBy) > 0 {
-		groupBy := p.buildGroupBy(paramGroupBy[0])
+	if paramGroupBy, ok := query[ctx.names.Group]; ok && len(paramGroupBy) > 0 {
+		groupBy := p.buildGroupBy(paramList{value: paramGroupBy[0]}, ctx)
+		result.GroupByClause = strings.Join(groupBy, ",")
+	}
+	if ctx.doc != nil && len(ctx.doc.Group) > 0 {
+		groupBy := p.buildGroupBy(paramList{items: ctx.doc.Group}, ctx)
 		result.GroupByClause = strings.Join(groupBy, ",")
 	}
 
 	// Select
 	var selectClause []string
-	if paramSelect, ok := query["f"]; ok && len(paramSelect) > 0 {
-		selectClause = p.buildSelectClause(paramSelect[0])
+	if paramSelect, ok := query[ctx.names.Select]; ok && len(paramSelect) > 0 {
+		selectClause = p.buildSelectClause(ctx.names.Select, paramList{value: paramSelect[0]}, ctx)
+	}
+	if param, value, ok := p.odataParam(query, ODataSelect, ctx); ok {
+		selectClause = append(selectClause, p.buildSelectClause(param, paramList{value: value}, ctx)...)
+	}
+	if ctx.doc != nil && len(ctx.doc.Select) > 0 {
+		selectClause = p.buildSelectClause(ctx.names.Select, paramList{items: ctx.doc.Select}, ctx)
+	}
+	if selectClause != nil {
 		result.SelectClause = strings.Join(selectClause, ",")
 	}
 
 	// Having clause
-	if paramHaving, ok := query["h"]; ok && len(paramHaving) > 0 {
-		result.HavingClause = p.buildHavingClause(paramHaving[0])
+	if paramHaving, ok := query[ctx.names.Having]; ok && len(paramHaving) > 0 {
+		result.HavingClause = p.buildHavingClause(paramHaving[0], ctx)
+	}
+	if ctx.doc != nil && ctx.doc.Having != nil {
+		having := &WhereClause{
+			Arguments:   make([]interface{}, 0),
+			ArgumentMap: make(map[string]interface{}),
+		}
+		having.Where, _, _ = p.renderDocument(ctx.names.Having, ctx.doc.Having, having, p.resolveHaving, ctx)
+		result.HavingClause = having
+	}
+
+	// Pagination
+	// Ex. limit=20&offset=40 or page=3&per_page=20
+	p.buildPagination(query, result, ctx)
+	if keyset {
+		// the cursor replaces the offset
+		result.Offset = 0
 	}
 
 	return result
 }
 
-func (p *Parser) buildWhereClause(field string, queryMapping map[string]string) (colName, where string, arg interface{}, ok bool) {
-	// Case-insensitive Contain
-	matches := queryPattern.FindStringSubmatch(field)
-	if len(matches) != 4 {
-		return "", "", nil, false
-	}
+// fieldResolver resolve the query field of an atom to the SQL column or
+// expression, or return the reason why it is rejected
+type fieldResolver func(field string) (string, Reason)
 
-	fn, ok := queryMapping[matches[1]]
+// buildWhereClause build the condition of an atom, appending the arguments
+// bound to its placeholders and their ArgumentMap entries to the clause
+func (p *Parser) buildWhereClause(atom string, resolve fieldResolver, clause *WhereClause, ctx *parseContext) (string, *AtomError) {
+	field, opName, value, ok := p.splitAtom(atom)
 	if !ok {
-		return "", "", nil, false
+		return "", &AtomError{Atom: atom, Reason: ReasonMalformed}
+	}
+	return p.buildCondition(atom, field, opName, value, "", resolve, clause, ctx)
+}
+
+// buildCondition build the condition of an atom split into field, operator
+// and value, the value is written with the quoting of the atoms. Front ends
+// with their own syntax, eg., RSQL, build their conditions with it.
+// valueType is the type of typed values, eg., JSON numbers, used for the
+// fields without FieldTypes entry.
+func (p *Parser) buildCondition(atom, field, opName, value string, valueType FieldType, resolve fieldResolver, clause *WhereClause, ctx *parseContext) (string, *AtomError) {
+	if !validQuoting(value) {
+		return "", &AtomError{Atom: atom, Reason: ReasonMalformed}
 	}
-	op, ok := operators[matches[2]]
+	reject := func(reason Reason) (string, *AtomError) {
+		return "", &AtomError{Atom: atom, Field: field, Operator: opName, Reason: reason}
+	}
+	if exceeds(p.Limits.MaxValueLength, len(value)) {
+		return reject(ReasonLimitExceeded)
+	}
+
+	fn, reason := resolve(field)
+	if reason != "" {
+		return reject(reason)
+	}
+	op, ok := p.Operator(opName)
 	if !ok {
-		return "", "", nil, false
-	}
-	ph := p.GetPlaceHolder(&p.Metadata, matches[1])
-	arg = op.ArgumentHandler(matches[3])
-	where = op.WhereClauseHandler(fn, ph)
-	colName = matches[1]
-	return
-}
-
-func (p *Parser) buildOrderby(param string, orderby []string) []string {
-	pattern := regexp.MustCompile("(-)(.*)")
-	for _, order := range strings.Split(param, ",") {
-		matches := pattern.FindStringSubmatch(order)
-		if len(matches) == 3 {
-			// DESC
-			if field, ok := p.Metadata.QueryMapping[matches[2]]; ok {
-				orderby = append(orderby, fmt.Sprintf("%s DESC", field))
-			}
-		} else {
-			// ASC
-			if field, ok := p.Metadata.QueryMapping[order]; ok {
-				orderby = append(orderby, fmt.Sprintf("%s ASC", field))
-			}
+		return reject(ReasonUnknownOperator)
+	}
+	if !p.operatorAllowed(field, opName) {
+		return reject(ReasonOperatorNotAllowed)
+	}
+	if op.NoArgument {
+		// eg., d__isnull__ or d__isnull__true, d__isnull__false is negated
+		where := op.WhereClauseHandler(fn, "")
+		if value = unquoteValue(value); value == "" {
+			return where, nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return reject(ReasonInvalidValue)
 		}
+		if !b {
+			where = "NOT (" + where + ")"
+		}
+		return where, nil
+	}
+	value, null, onlyNull := stripNull(value, op.List)
+	if null && op.NullHandler == nil {
+		return reject(ReasonInvalidValue)
+	}
+	if onlyNull {
+		return op.NullHandler(fn, ""), nil
+	}
+	if !op.List {
+		value = unquoteValue(value)
+	}
+	arg := op.ArgumentHandler(value)
+	if exceeds(p.Limits.MaxListSize, listLen(arg)) {
+		return reject(ReasonLimitExceeded)
+	}
+	t, ok := p.Metadata.FieldTypes[field]
+	if !ok && valueType != "" {
+		t, ok = valueType, true
 	}
+	if ok && !op.Pattern {
+		var err error
+		if arg, err = convertArgument(t, arg, p.Metadata.TimeLocation, ctx.now); err != nil {
+			return reject(ReasonInvalidValue)
+		}
+	}
+	if op.Range {
+		if listLen(arg) != 2 {
+			return reject(ReasonInvalidValue)
+		}
+		// one key per bound, eg., a and a_2
+		bounds := reflect.ValueOf(arg)
+		ph := make([]string, 2)
+		for i := range ph {
+			bound := bounds.Index(i).Interface()
+			name, key := p.argName(field, ctx)
+			ph[i] = p.placeholder(name, ctx)
+			clause.Arguments = append(clause.Arguments, bound)
+			clause.ArgumentMap[key] = bound
+		}
+		return op.WhereClauseHandler(fn, ph[0]+" AND "+ph[1]), nil
+	}
+	name, key := p.argName(field, ctx)
+	var ph string
+	ph, clause.Arguments = p.bindArgument(name, arg, clause.Arguments, ctx)
+	clause.ArgumentMap[key] = arg
+	where := op.WhereClauseHandler(fn, ph)
+	if null {
+		where = op.NullHandler(fn, where)
+	}
+	return where, nil
+}
+
+// splitAtom split an atom into field, operator and value. The value starts
+// after the first known operator, so it can contain `__`, eg., n__eq__a__b
+// => n, eq, a__b, while aggregates keep theirs, eg., a__sum__gt__1 =>
+// a__sum, gt, 1. Atoms without known operator are split by matchAtom.
+func (p *Parser) splitAtom(atom string) (field, op, value string, ok bool) {
+	for i := strings.Index(atom, "__"); i > 0; {
+		rest := atom[i+2:]
+		j := strings.Index(rest, "__")
+		if j < 0 {
+			break
+		}
+		field, op := atom[:i], rest[:j]
+		if _, ok := p.Operator(op); ok && isWord(field) && isWord(op) {
+			return field, op, rest[j+2:], true
+		}
+		i += 2 + j
+	}
+	return matchAtom(atom)
+}
+
+func (p *Parser) buildOrderby(list paramList, orderby []string, ctx *parseContext) []string {
+	list.each(func(i, pos int, order string) bool {
+		if exceeds(p.Limits.MaxSortKeys, i+1) {
+			ctx.reject(ctx.names.Sort, pos, &AtomError{Atom: list.rest(i, pos), Reason: ReasonLimitExceeded})
+			return false
+		}
+		name := strings.TrimPrefix(order, "-")
+		if column, ok := p.orderColumn(ctx.names.Sort, pos, order, name, ctx); ok {
+			orderby = append(orderby, column+direction(name != order))
+		}
+		return true
+	})
 
 	return orderby
 }
 
-func (p *Parser) buildGroupBy(param string) []string {
-	groupby := make([]string, 0)
-	for _, item := range strings.Split(param, ",") {
-		if field, ok := p.Metadata.QueryMapping[item]; ok {
-			groupby = append(groupby, field)
+// orderColumn resolve the column of a sort key, fields which cannot be
+// sorted are rejected
+func (p *Parser) orderColumn(param string, pos int, atom, name string, ctx *parseContext) (string, bool) {
+	column, reason := p.resolveField(name)
+	if reason == "" && !fieldListed(p.Metadata.SortableFields, name) {
+		reason = ReasonFieldNotAllowed
+	}
+	if reason != "" {
+		if len(atom) > 0 {
+			ctx.reject(param, pos, &AtomError{Atom: atom, Field: name, Reason: reason})
 		}
+		return "", false
+	}
+	return column, true
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
 	}
+	return " ASC"
+}
+
+func (p *Parser) buildGroupBy(list paramList, ctx *parseContext) []string {
+	groupby := make([]string, 0)
+	list.each(func(i, pos int, item string) bool {
+		if exceeds(p.Limits.MaxGroupKeys, i+1) {
+			ctx.reject(ctx.names.Group, pos, &AtomError{Atom: list.rest(i, pos), Reason: ReasonLimitExceeded})
+			return false
+		}
+		if column, reason := p.resolveField(item); reason != "" {
+			if len(item) > 0 {
+				ctx.reject(ctx.names.Group, pos, &AtomError{Atom: item, Field: item, Reason: reason})
+			}
+		} else if !fieldListed(p.Metadata.GroupableFields, item) {
+			ctx.reject(ctx.names.Group, pos, &AtomError{Atom: item, Field: item, Reason: ReasonFieldNotAllowed})
+		} else {
+			groupby = append(groupby, column)
+		}
+		return true
+	})
 
 	return groupby
 }
 
-func (p *Parser) buildSelectClause(param string) []string {
+func (p *Parser) buildSelectClause(param string, list paramList, ctx *parseContext) []string {
 	clause := make([]string, 0)
 
-	var aggregrateFns map[string]string
-	if p.Metadata.AggregateFunctions == nil {
-		aggregrateFns = defaultAggregateFunctions
-	} else {
-		aggregrateFns = p.Metadata.AggregateFunctions
-	}
-
-	for _, item := range strings.Split(param, ",") {
-		if field, ok := p.Metadata.QueryMapping[item]; ok {
-			clause = append(clause, field)
-		} else {
-			// check if using aggregate functions
-			// loop over all aggregate functions
-			for k, fn := range aggregrateFns {
-				pattern := regexp.MustCompile(fmt.Sprintf(`(\w+)__%s`, k))
-				matches := pattern.FindStringSubmatch(item)
-				if len(matches) == 2 {
-					clause = append(clause, fmt.Sprintf("%s(%s) AS %s", fn, matches[1], item))
-				}
+	list.each(func(i, pos int, item string) bool {
+		if exceeds(p.Limits.MaxSelectColumns, i+1) {
+			ctx.reject(param, pos, &AtomError{Atom: list.rest(i, pos), Reason: ReasonLimitExceeded})
+			return false
+		}
+		item = strings.TrimSpace(item)
+		if column, reason := p.resolveField(item); reason == "" {
+			clause = append(clause, column)
+		} else if len(item) > 0 {
+			// aggregate functions, eg., a__sum => SUM(a) AS a__sum
+			if aggregate, reason := p.resolveAggregate(item); reason != "" {
+				ctx.reject(param, pos, &AtomError{Atom: item, Field: item, Reason: reason})
+			} else {
+				clause = append(clause, aggregate+" AS "+p.quoteColumn(item))
 			}
 		}
-	}
+		return true
+	})
 
 	return clause
 }
 
 // Build HAVING clause
 // eg., h=a__sum__lt__1|b__count__gt__0&f=a__sum
-// => [a__sum, lt, 1], [COUNT(b), gt, 0]
+// => [SUM(a), lt, 1], [COUNT(b), gt, 0]
 //
-// Steps:
-// 1. check if the column name exist in the SELECT clause
-// 2. If not exist, then build the column with aggrgrate function
-// 3. go through the where clause building workflow
-func (p *Parser) buildHavingClause(param string) *WhereClause {
-	whereClause := &WhereClause{}
-	args := make([]interface{}, 0)
-	argMap := make(map[string]interface{})
-	where := make([]string, 0)
+// Fields are either query fields, or aggregates of query fields
+// resolved as in the SELECT clause.
+func (p *Parser) buildHavingClause(param string, ctx *parseContext) *WhereClause {
+	whereClause := &WhereClause{
+		Arguments:   make([]interface{}, 0),
+		ArgumentMap: make(map[string]interface{}),
+	}
+
+	whereClause.Where, _, _ = p.renderParam(ctx.names.Having, param, p.exprParser(), whereClause, p.resolveHaving, ctx)
+
+	return whereClause
+}
+
+// resolveAggregate resolve an aggregate of a query field, eg., a__sum => SUM(age).
+// Only mapped fields listed in AggregatableFields can be aggregated, so the
+// alias is always a plain identifier and the column always comes from
+// QueryMapping.
+func (p *Parser) resolveAggregate(alias string) (string, Reason) {
+	if aggregate, ok := p.aggregates[alias]; ok {
+		return aggregate, ""
+	}
+	i := strings.LastIndex(alias, "__")
+	if i <= 0 || !isIdentifier(alias) {
+		return "", ReasonUnknownField
+	}
+	name, key := alias[:i], alias[i+2:]
+	column, ok := p.Metadata.QueryMapping[name]
+	if !ok {
+		return "", ReasonUnknownField
+	}
+	fn, ok := p.aggregateFunctions()[key]
+	if !ok {
+		return "", ReasonUnknownAggregate
+	}
+	if !fieldListed(p.Metadata.AggregatableFields, name) {
+		return "", ReasonFieldNotAllowed
+	}
+	return fn + "(" + p.quoteColumn(column) + ")", ""
+}
+
+// resolveField resolve a query field to its column
+func (p *Parser) resolveField(field string) (string, Reason) {
+	if column, ok := p.columns[field]; ok {
+		return column, ""
+	}
+	if column, ok := p.Metadata.QueryMapping[field]; ok {
+		return p.quoteColumn(column), ""
+	}
+	return "", ReasonUnknownField
+}
 
-	queryMapping := make(map[string]string)
-	for k, v := range p.Metadata.QueryMapping {
-		queryMapping[k] = v
+// resolveHaving resolve a field of the HAVING clause, either a query field
+// or an aggregate of a query field
+func (p *Parser) resolveHaving(field string) (string, Reason) {
+	if column, reason := p.resolveField(field); reason == "" {
+		return column, ""
 	}
+	return p.resolveAggregate(field)
+}
 
-	var aggregrateFns map[string]string
-	if p.Metadata.AggregateFunctions == nil {
-		aggregrateFns = defaultAggregateFunctions
-	} else {
-		aggregrateFns = p.Metadata.AggregateFunctions
+// exprParserFunc parse the boolean expression of a parameter, and return
+// the text which is not part of it as malformed atoms
+type exprParserFunc func(src string) (*exprNode, []*exprNode)
+
+// exprParser parser of the q and h expressions, see Parser.Syntax
+func (p *Parser) exprParser() exprParserFunc {
+	if p.Syntax == SyntaxRSQL {
+		return parseRSQL
 	}
+	return parseExpr
+}
 
-	for _, field := range strings.Split(param, "|") {
-		for k, fn := range aggregrateFns {
-			pattern := regexp.MustCompile(fmt.Sprintf(`(\w+)__%s`, k))
-			matches := pattern.FindStringSubmatch(field)
-			if len(matches) == 2 {
-				if fieldName, ok := queryMapping[matches[1]]; ok {
-					f := fmt.Sprintf("%s(%s)", fn, fieldName)
-					queryMapping[matches[0]] = f
+// renderParam parse and render the boolean expression of the given parameter,
+// resolving atom fields with the resolver
+func (p *Parser) renderParam(param, value string, parse exprParserFunc, clause *WhereClause, resolve fieldResolver, ctx *parseContext) (string, exprKind, bool) {
+	expr, malformed := parse(value)
+	return p.renderTree(param, value, expr, malformed, clause, resolve, ctx)
+}
+
+// renderTree render the expression tree of the given parameter, value is
+// the text of the parameter reported when the tree is over the limits
+// the whole parameter is FalseCondition if a part of it is over the limits
+func (p *Parser) renderTree(param, value string, expr *exprNode, malformed []*exprNode, clause *WhereClause, resolve fieldResolver, ctx *parseContext) (string, exprKind, bool) {
+	m := ctx.mark(clause)
+	where, kind, ok := p.renderNodes(param, value, expr, malformed, clause, resolve, ctx)
+	if where, failed := ctx.failClosed(m, clause, where); failed {
+		return where, exprAtom, true
+	}
+	return where, kind, ok
+}
+
+func (p *Parser) renderNodes(param, value string, expr *exprNode, malformed []*exprNode, clause *WhereClause, resolve fieldResolver, ctx *parseContext) (string, exprKind, bool) {
+	if atoms, depth := expr.size(); exceeds(p.Limits.MaxConditions, atoms) || exceeds(p.Limits.MaxDepth, depth) {
+		ctx.reject(param, 0, &AtomError{Atom: value, Reason: ReasonLimitExceeded})
+		return "", exprAtom, false
+	}
+	for _, node := range malformed {
+		err := &AtomError{Atom: node.atom, Reason: ReasonMalformed}
+		if parts := node.parts; parts != nil {
+			err.Field, err.Operator = parts.field, parts.op
+			if parts.reason != "" {
+				err.Reason = parts.reason
+			}
+			if parts.stop {
+				if ctx.stopped == nil {
+					ctx.stopped = map[*AtomError]bool{}
 				}
-				break
+				ctx.stopped[err] = true
 			}
 		}
+		ctx.reject(param, node.pos, err)
+	}
 
-		col, wh, arg, ok := p.buildWhereClause(field, queryMapping)
-		if !ok {
-			continue
+	return renderExpr(expr, clause, func(node *exprNode, clause *WhereClause) (string, bool) {
+		if len(node.atom) == 0 {
+			return "", false
 		}
-		where = append(where, wh)
-		args = append(args, arg)
-		argMap[p.GetArgMapKey(&p.Metadata, col)] = arg
+		var wh string
+		var err *AtomError
+		if node.parts != nil {
+			wh, err = p.buildCondition(node.atom, node.parts.field, node.parts.op, node.parts.value, node.parts.valueType, resolve, clause, ctx)
+		} else {
+			wh, err = p.buildWhereClause(node.atom, resolve, clause, ctx)
+		}
+		if err != nil {
+			ctx.reject(param, node.pos, err)
+			return "", false
+		}
+		return wh, true
+	})
+}
+
+// operatorAllowed check if the operator can be used with the query field
+func (p *Parser) operatorAllowed(field, op string) bool {
+	allowed, ok := p.Metadata.AllowedOperators[field]
+	if !ok {
+		// aggregate alias, eg., a__sum
+		if i := strings.LastIndex(field, "__"); i > 0 {
+			if _, isAggregate := p.aggregateFunctions()[field[i+2:]]; isAggregate {
+				return p.operatorAllowed(field[:i], op)
+			}
+		}
+		allowed = p.Metadata.DefaultAllowedOperators
+		if allowed == nil {
+			return true
+		}
+	}
+	for _, name := range allowed {
+		if name == op {
+			return true
+		}
+	}
+	return false
+}
+
+// searchConditions merge ordered conditions with the conditions of a search
+// map, sorted by key so the generated clause is always the same
+func searchConditions(conds []Condition, search map[string]interface{}) []Condition {
+	if len(search) == 0 {
+		return conds
 	}
+	keys := make([]string, 0, len(search))
+	for key := range search {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	whereClause.ArgumentMap = argMap
-	whereClause.Arguments = args
-	whereClause.Where = strings.Join(where, " AND ")
+	merged := make([]Condition, 0, len(conds)+len(keys))
+	merged = append(merged, conds...)
+	for _, key := range keys {
+		merged = append(merged, Condition{Where: key, Args: []interface{}{search[key]}})
+	}
+	return merged
+}
 
-	return whereClause
+// fieldListed check if the query field is in the list, a nil list accept any field
+func fieldListed(fields []string, field string) bool {
+	if fields == nil {
+		return true
+	}
+	for _, name := range fields {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) aggregateFunctions() map[string]string {
+	if p.Metadata.AggregateFunctions == nil {
+		return defaultAggregateFunctions
+	}
+	return p.Metadata.AggregateFunctions
 }
 
 func DefaultArgumentHandler(arg string) interface{} {
@@ -459,9 +1000,48 @@ func defaultArgMapFunc(md *MetaData, fieldname string) string {
 	if v, ok := md.QueryMapping[fieldname]; ok {
 		return v
 	}
+	// n-th argument of a field, eg., a_2 => age_2
+	if i := strings.LastIndexByte(fieldname, '_'); i > 0 && isDigits(fieldname[i+1:]) {
+		if v, ok := md.QueryMapping[fieldname[:i]]; ok {
+			return v + fieldname[i:]
+		}
+	}
 	return fieldname
 }
 
+// argName name of the next argument of the field, given to GetArgMapKey and
+// GetPlaceHolder, and its ArgumentMap key. Keys are unique within a parse,
+// the n-th argument of a field is named field_n, eg., a, a_2, a_3.
+func (p *Parser) argName(field string, ctx *parseContext) (name, key string) {
+	if ctx.keys == nil {
+		ctx.keys = make(map[string]int)
+	}
+	argMapKey := p.GetArgMapKey
+	if argMapKey == nil {
+		argMapKey = defaultArgMapFunc
+	}
+	base := argMapKey(&p.Metadata, field)
+	name, key = field, base
+	for n := 2; ctx.keys[key] > 0; n++ {
+		name = fmt.Sprintf("%s_%d", field, n)
+		if key = argMapKey(&p.Metadata, name); key == base {
+			// the key function ignores the suffix
+			key = fmt.Sprintf("%s_%d", base, n)
+		}
+	}
+	ctx.keys[key] = len(ctx.keys) + 1
+	return name, key
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
 func defaultPlaceHolderFunc(_ *MetaData, _ string) string {
 	return "?"
 }
@@ -82,7 +82,7 @@ func TestBuildWithCustomPlaceholder(t *testing.T) {
 		t.Fatalf("exp: no err, got: %v", err)
 	}
 
-	exp := "a = $a AND b > $b AND c >= $c AND d < $d AND e <= $e AND LOWER(f) LIKE $f AND g IN ($g) AND h NOT IN ($h) AND i <> $i AND j LIKE $j AND l LIKE $l"
+	exp := "a = $a AND b > $b AND c >= $c AND d < $d AND e <= $e AND LOWER(f) LIKE $f ESCAPE '!' AND g IN ($g) AND h NOT IN ($h) AND i <> $i AND j LIKE $j ESCAPE '!' AND l LIKE $l ESCAPE '!'"
 	if res.WhereClause.Where != exp {
 		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
 	}
@@ -159,7 +159,7 @@ func TestBuildQueryStringSuccessWithQueryString(t *testing.T) {
 		t.Fatalf("exp: no err, got: %v", err)
 	}
 
-	exp := "a = ? AND b > ? AND c >= ? AND d < ? AND e <= ? AND d LIKE ? AND LOWER(f) LIKE ? AND g IN (?) AND h NOT IN (?) AND i <> ? AND j LIKE ? AND l LIKE ?"
+	exp := "a = ? AND b > ? AND c >= ? AND d < ? AND e <= ? AND d LIKE ? ESCAPE '!' AND LOWER(f) LIKE ? ESCAPE '!' AND g IN (?) AND h NOT IN (?) AND i <> ? AND j LIKE ? ESCAPE '!' AND l LIKE ? ESCAPE '!'"
 	if res.WhereClause.Where != exp {
 		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
 	}
@@ -227,7 +227,7 @@ func TestBuildQueryString(t *testing.T) {
 		t.Fatalf("exp: no err, got: %v", err)
 	}
 
-	exp := "a = ? AND b > ? AND c >= ? AND d < ? AND e <= ? AND d LIKE ? AND LOWER(f) LIKE ? AND g IN (?) AND h NOT IN (?) AND i <> ?"
+	exp := "a = ? AND b > ? AND c >= ? AND d < ? AND e <= ? AND d LIKE ? ESCAPE '!' AND LOWER(f) LIKE ? ESCAPE '!' AND g IN (?) AND h NOT IN (?) AND i <> ?"
 	if res.WhereClause.Where != exp {
 		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
 	}
@@ -265,7 +265,7 @@ func TestBuildQuerySuccess(t *testing.T) {
 	u, _ := url.ParseRequestURI("http://abc.com?q=a__eq__1|b__gt__2|c__gte__3|d__lt__4|e__lte__5|d__co__abc|f__ico__cde|g__in__[a,b,c]|h__ni__[a,b,c]|i__ne__h")
 	res := p.Parse(u.Query())
 
-	exp := "a = ? AND b > ? AND c >= ? AND d < ? AND e <= ? AND d LIKE ? AND LOWER(f) LIKE ? AND g IN (?) AND h NOT IN (?) AND i <> ?"
+	exp := "a = ? AND b > ? AND c >= ? AND d < ? AND e <= ? AND d LIKE ? ESCAPE '!' AND LOWER(f) LIKE ? ESCAPE '!' AND g IN (?) AND h NOT IN (?) AND i <> ?"
 	if res.WhereClause.Where != exp {
 		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
 	}
@@ -525,7 +525,7 @@ func TestParseQueryWithMapping(t *testing.T) {
 	u, _ := url.ParseRequestURI("http://abc.com?q=a__eq__1|b__ne__2|n__co__peter")
 	res := p.Parse(u.Query())
 
-	expWhere := "age = ? AND b <> ? AND name LIKE ?"
+	expWhere := "age = ? AND b <> ? AND name LIKE ? ESCAPE '!'"
 	expArgs := []interface{}{
 		"1",
 		"2",
@@ -568,7 +568,7 @@ func TestParseQueryWithMappingAndDefaultGetMapKey(t *testing.T) {
 	u, _ := url.ParseRequestURI("http://abc.com?q=a__eq__1|b__ne__2|n__co__peter")
 	res := p.Parse(u.Query())
 
-	expWhere := "age = ? AND b <> ? AND name LIKE ?"
+	expWhere := "age = ? AND b <> ? AND name LIKE ? ESCAPE '!'"
 	expArgs := []interface{}{
 		"1",
 		"2",
@@ -873,6 +873,179 @@ func TestParseDatetime(t *testing.T) {
 	}
 }
 
+func TestParseAllowedOperators(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"n": "name",
+		"u": "uuid",
+		"a": "age",
+	}
+	p.Metadata.AllowedOperators = map[string][]string{
+		"n": {"eq", "sw"},
+		"u": {"eq", "in"},
+	}
+
+	res, _ := p.ParseQuery("q=n__co__x|n__sw__y|u__lt__1|u__in__[1,2]|a__ico__3")
+
+	exp := "name LIKE ? ESCAPE '!' AND uuid IN (?) AND LOWER(age) LIKE ? ESCAPE '!'"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+
+	// fields not listed fallback to the default operators
+	p.Metadata.DefaultAllowedOperators = []string{"eq", "gt", "lt"}
+	qv, _ := url.ParseQuery("q=n__co__x|a__gt__1|a__ico__3")
+	_, err := p.ParseStrict(qv)
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("exp: *ParseError, got: %v", err)
+	}
+	expErrs := []*AtomError{
+		{Param: "q", Position: 0, Atom: "n__co__x", Field: "n", Operator: "co", Reason: ReasonOperatorNotAllowed},
+		{Param: "q", Position: 18, Atom: "a__ico__3", Field: "a", Operator: "ico", Reason: ReasonOperatorNotAllowed},
+	}
+	if !reflect.DeepEqual(perr.Errors, expErrs) {
+		t.Fatalf("exp: %v, got: %v", expErrs, perr.Errors)
+	}
+}
+
+func TestParseHavingAllowedOperators(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "a",
+		"b": "b",
+	}
+	p.Metadata.AllowedOperators = map[string][]string{
+		"a":        {"gt"},
+		"b":        {"eq"},
+		"b__count": {"lt"},
+	}
+
+	res, _ := p.ParseQuery("h=a__sum__gt__1|a__max__lt__2|b__count__lt__3|b__count__eq__4")
+
+	exp := "SUM(a) > ? AND COUNT(b) < ?"
+	if res.HavingClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.HavingClause.Where)
+	}
+	exparg := []interface{}{"1", "3"}
+	if !reflect.DeepEqual(res.HavingClause.Arguments, exparg) {
+		t.Fatalf("exp: %v, got: %v", exparg, res.HavingClause.Arguments)
+	}
+}
+
+func TestParseSelectAggregateUsesMapping(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+		"n": "name",
+	}
+
+	res, _ := p.ParseQuery("f=n,a__sum,a__max&h=a__avg__gt__1")
+
+	exp := "name,SUM(age) AS a__sum,MAX(age) AS a__max"
+	if res.SelectClause != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.SelectClause)
+	}
+	exp = "AVG(age) > ?"
+	if res.HavingClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.HavingClause.Where)
+	}
+}
+
+func TestParseHostileAggregates(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+		"n": "name",
+	}
+	p.Metadata.AggregatableFields = []string{"a"}
+
+	hostile := []string{
+		"f=a);DROP TABLE users;--__sum",
+		"f=age__sum",
+		"f=a__sum) FROM users;--",
+		"f=1 AS x, password__sum",
+		"f=n__sum",
+		"f=a__foo",
+		"f=a__sum AS x",
+		"h=x);drop__sum__gt__1",
+		"h=a__sum) OR (1=1__gt__1",
+		"h=password__count__gt__0",
+		"h=n__count__gt__0",
+	}
+	for _, query := range hostile {
+		// build the values by hand, url.ParseQuery rejects `;`
+		qv := url.Values{query[:1]: {query[2:]}}
+		res := p.Parse(qv)
+		if res.SelectClause != "" || res.HavingClause.Where != "" {
+			t.Fatalf("exp: %q rejected, got: select %q, having %q", query, res.SelectClause, res.HavingClause.Where)
+		}
+		if _, err := p.ParseStrict(qv); err == nil {
+			t.Fatalf("exp: %q reported, got: nil", query)
+		}
+	}
+
+	qv, _ := url.ParseQuery("f=n__sum,a__foo,b__sum")
+	_, err := p.ParseStrict(qv)
+	perr := err.(*ParseError)
+	exp := []*AtomError{
+		{Param: "f", Position: 0, Atom: "n__sum", Field: "n__sum", Reason: ReasonFieldNotAllowed},
+		{Param: "f", Position: 7, Atom: "a__foo", Field: "a__foo", Reason: ReasonUnknownAggregate},
+		{Param: "f", Position: 14, Atom: "b__sum", Field: "b__sum", Reason: ReasonUnknownField},
+	}
+	if !reflect.DeepEqual(perr.Errors, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, perr.Errors)
+	}
+}
+
+func TestForceAndDefaultSearchOrdering(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "a",
+	}
+	p.Metadata.ForceSearch = map[string]interface{}{
+		"tenant = ?": 1,
+		"active = ?": true,
+		"region = ?": "eu",
+		"kind = ?":   "x",
+	}
+	p.Metadata.ForceConditions = []Condition{
+		{Where: "deleted_at IS NULL"},
+		{Where: "owner BETWEEN ? AND ?", Args: []interface{}{7, 9}},
+	}
+	p.Metadata.DefaultSearch = map[string]interface{}{
+		"z = ?": 26,
+		"b = ?": 2,
+		"m = ?": 13,
+	}
+
+	exp := "deleted_at IS NULL AND owner BETWEEN ? AND ? AND active = ? AND kind = ? AND region = ? AND tenant = ? AND b = ? AND m = ? AND z = ?"
+	expArgs := []interface{}{7, 9, true, "x", "eu", 1, 2, 13, 26}
+	for i := 0; i < 20; i++ {
+		res, _ := p.ParseQuery("")
+		if res.WhereClause.Where != exp {
+			t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, expArgs) {
+			t.Fatalf("exp: %v, got: %v", expArgs, res.WhereClause.Arguments)
+		}
+	}
+
+	p.Metadata.DefaultConditions = []Condition{
+		{Where: "archived = ?", Args: []interface{}{false}},
+	}
+	res, _ := p.ParseQuery("")
+	expArgMap := map[string]interface{}{
+		"archived = ?": false,
+		"b = ?":        2,
+		"m = ?":        13,
+		"z = ?":        26,
+	}
+	if !reflect.DeepEqual(res.WhereClause.ArgumentMap, expArgMap) {
+		t.Fatalf("exp: %v, got: %v", expArgMap, res.WhereClause.ArgumentMap)
+	}
+}
+
 func BenchmarkParser(b *testing.B) {
 	md := MetaData{
 		QueryMapping: map[string]string{
@@ -901,3 +1074,110 @@ func BenchmarkParser(b *testing.B) {
 		p.ParseQuery("q=a__eq__1|b__gt__2|c__gte__3|d__lt__4|e__lte__5|d__co__abc|f__ico__cde|g__in__[a,b,c]|h__ni__[a,b,c]|i__ne__h|j__sw__k|l__ew__m")
 	}
 }
+
+func benchmarkParser() *Parser {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{
+		"a": "age",
+		"n": "name",
+		"c": "created_at",
+		"s": "status",
+	}
+	p.Metadata.FieldTypes = map[string]FieldType{"a": TypeInt}
+	return p
+}
+
+func benchmarkParam(b *testing.B, p *Parser, query string) {
+	qv, err := url.ParseQuery(query)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Parse(qv)
+	}
+}
+
+func BenchmarkWhere(b *testing.B) {
+	benchmarkParam(b, benchmarkParser(), "q=a__gte__18|(n__co__abc||s__in__[a,b,c])|!c__isnull__")
+}
+
+func BenchmarkOrderBy(b *testing.B) {
+	benchmarkParam(b, benchmarkParser(), "s=-a,n,c")
+}
+
+func BenchmarkGroupBy(b *testing.B) {
+	benchmarkParam(b, benchmarkParser(), "g=n,s")
+}
+
+func BenchmarkSelect(b *testing.B) {
+	benchmarkParam(b, benchmarkParser(), "f=n,s,a__sum,a__max")
+}
+
+func BenchmarkHaving(b *testing.B) {
+	benchmarkParam(b, benchmarkParser(), "h=a__sum__gt__10|a__count__lte__5")
+}
+
+func BenchmarkPagination(b *testing.B) {
+	benchmarkParam(b, benchmarkParser(), "limit=20&offset=40")
+}
+
+func BenchmarkDialect(b *testing.B) {
+	p := benchmarkParser()
+	p.Dialect = Postgres
+	benchmarkParam(b, p, "q=a__gte__18|s__in__[a,b,c]&s=-a&f=n,a__sum&g=n&h=a__sum__gt__10")
+}
+
+func BenchmarkCompiled(b *testing.B) {
+	c := benchmarkParser().MustCompile()
+	qv, _ := url.ParseQuery("q=a__gte__18|s__in__[a,b,c]&s=-a&f=n,a__sum&g=n&h=a__sum__gt__10")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Parse(qv)
+	}
+}
+
+func TestParseArgumentMapUnique(t *testing.T) {
+	p := NewParser()
+	p.GetPlaceHolder = NamedPlaceHolder
+	p.Metadata.QueryMapping = map[string]string{
+		"a":               "age",
+		"created_at_from": "created_at",
+		"created_at_to":   "created_at",
+	}
+
+	res, _ := p.ParseQuery("q=a__gte__18|a__lte__65|created_at_from__gte__2021|created_at_to__lte__2022&h=a__gt__1")
+	exp := "age >= :age AND age <= :age_2 AND created_at >= :created_at AND created_at <= :created_at_2"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	expArgMap := map[string]interface{}{
+		"age":          "18",
+		"age_2":        "65",
+		"created_at":   "2021",
+		"created_at_2": "2022",
+	}
+	if !reflect.DeepEqual(res.WhereClause.ArgumentMap, expArgMap) {
+		t.Fatalf("exp: %v, got: %v", expArgMap, res.WhereClause.ArgumentMap)
+	}
+
+	// keys are unique across WHERE and HAVING
+	if res.HavingClause.Where != "age > :age_3" {
+		t.Fatalf("exp: %v, got: %v", "age > :age_3", res.HavingClause.Where)
+	}
+	if !reflect.DeepEqual(res.HavingClause.ArgumentMap, map[string]interface{}{"age_3": "1"}) {
+		t.Fatalf("exp: %v, got: %v", map[string]interface{}{"age_3": "1"}, res.HavingClause.ArgumentMap)
+	}
+
+	// key functions ignoring the suffix
+	p.GetArgMapKey = func(md *MetaData, fieldname string) string {
+		return "k"
+	}
+	res, _ = p.ParseQuery("q=a__gte__18|a__lte__65")
+	expArgMap = map[string]interface{}{"k": "18", "k_2": "65"}
+	if !reflect.DeepEqual(res.WhereClause.ArgumentMap, expArgMap) {
+		t.Fatalf("exp: %v, got: %v", expArgMap, res.WhereClause.ArgumentMap)
+	}
+}
@@ -0,0 +1,182 @@
+package djolar
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relative time values of TypeTime fields, evaluated against Parser.Now in
+// MetaData.TimeLocation:
+//
+// 	value  = anchor { offset } | [ sign ] duration
+// 	anchor = now | today | startOfDay | startOfWeek | startOfMonth | startOfYear
+// 	offset = sign ( number unit | duration )
+// 	unit   = s | m | h | d | w | M | y
+//
+// duration is an ISO 8601 duration, eg., P1M, PT12H, P1DT2H30M
+// eg., now-7d, startOfMonth-1M, startOfDay+PT9H, -P7D
+
+var timeAnchors = map[string]func(now time.Time) time.Time{
+	"now": func(now time.Time) time.Time {
+		return now
+	},
+	"today":      startOfDay,
+	"startofday": startOfDay,
+	"startofweek": func(now time.Time) time.Time {
+		// weeks start on monday
+		return startOfDay(now).AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	},
+	"startofmonth": func(now time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	},
+	"startofyear": func(now time.Time) time.Time {
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+	},
+}
+
+func startOfDay(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// parseRelativeTime evaluate a relative time value
+func parseRelativeTime(value string, now time.Time) (time.Time, bool) {
+	i := 0
+	for i < len(value) && isLetter(value[i]) {
+		i++
+	}
+	t, rest := now, value
+	if anchor, ok := timeAnchors[strings.ToLower(value[:i])]; ok {
+		t, rest = anchor(now), value[i:]
+	} else if strings.HasPrefix(value, "P") {
+		rest = "+" + value
+	} else if !strings.HasPrefix(value, "+P") && !strings.HasPrefix(value, "-P") {
+		return time.Time{}, false
+	}
+
+	for len(rest) > 0 {
+		sign := 1
+		switch rest[0] {
+		case '+':
+		case '-':
+			sign = -1
+		default:
+			return time.Time{}, false
+		}
+		rest = rest[1:]
+		end := strings.IndexAny(rest, "+-")
+		if end < 0 {
+			end = len(rest)
+		}
+		var ok bool
+		if t, ok = addOffset(t, rest[:end], sign); !ok {
+			return time.Time{}, false
+		}
+		rest = rest[end:]
+	}
+	return t, true
+}
+
+// addOffset add a number with unit, or an ISO 8601 duration, to the time
+func addOffset(t time.Time, offset string, sign int) (time.Time, bool) {
+	if strings.HasPrefix(offset, "P") {
+		return addISODuration(t, offset[1:], sign)
+	}
+	if len(offset) < 2 {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(offset[:len(offset)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	n *= sign
+	switch offset[len(offset)-1] {
+	case 's':
+		return t.Add(time.Duration(n) * time.Second), true
+	case 'm':
+		return t.Add(time.Duration(n) * time.Minute), true
+	case 'h':
+		return t.Add(time.Duration(n) * time.Hour), true
+	case 'd':
+		return t.AddDate(0, 0, n), true
+	case 'w':
+		return t.AddDate(0, 0, 7*n), true
+	case 'M':
+		return t.AddDate(0, n, 0), true
+	case 'y':
+		return t.AddDate(n, 0, 0), true
+	}
+	return time.Time{}, false
+}
+
+// addISODuration add an ISO 8601 duration without its leading P, eg., 1DT2H
+func addISODuration(t time.Time, duration string, sign int) (time.Time, bool) {
+	if len(duration) == 0 || duration == "T" {
+		return time.Time{}, false
+	}
+	var years, months, days int
+	var clock time.Duration
+	inTime := false
+	for len(duration) > 0 {
+		if duration[0] == 'T' {
+			if inTime {
+				return time.Time{}, false
+			}
+			inTime = true
+			duration = duration[1:]
+			continue
+		}
+		i := 0
+		for i < len(duration) && (isDigit(duration[i]) || duration[i] == '.') {
+			i++
+		}
+		if i == 0 || i == len(duration) {
+			return time.Time{}, false
+		}
+		number, unit := duration[:i], duration[i]
+		duration = duration[i+1:]
+
+		if inTime {
+			f, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			switch unit {
+			case 'H':
+				clock += time.Duration(f * float64(time.Hour))
+			case 'M':
+				clock += time.Duration(f * float64(time.Minute))
+			case 'S':
+				clock += time.Duration(f * float64(time.Second))
+			default:
+				return time.Time{}, false
+			}
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return time.Time{}, false
+		}
+		switch unit {
+		case 'Y':
+			years += n
+		case 'M':
+			months += n
+		case 'W':
+			days += 7 * n
+		case 'D':
+			days += n
+		default:
+			return time.Time{}, false
+		}
+	}
+	return t.AddDate(sign*years, sign*months, sign*days).Add(time.Duration(sign) * clock), true
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
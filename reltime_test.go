@@ -0,0 +1,125 @@
+package djolar
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRelativeTime(t *testing.T) {
+	// wednesday
+	now := time.Date(2021, 3, 17, 15, 4, 5, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		"now":                 now,
+		"now-7d":              time.Date(2021, 3, 10, 15, 4, 5, 0, time.UTC),
+		"now+1h-30m":          time.Date(2021, 3, 17, 15, 34, 5, 0, time.UTC),
+		"now-1y+2M":           time.Date(2020, 5, 17, 15, 4, 5, 0, time.UTC),
+		"now-2w":              time.Date(2021, 3, 3, 15, 4, 5, 0, time.UTC),
+		"now-10s":             time.Date(2021, 3, 17, 15, 3, 55, 0, time.UTC),
+		"today":               time.Date(2021, 3, 17, 0, 0, 0, 0, time.UTC),
+		"startOfDay":          time.Date(2021, 3, 17, 0, 0, 0, 0, time.UTC),
+		"startOfWeek":         time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC),
+		"startOfMonth":        time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		"startOfMonth-1M":     time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		"startOfYear":         time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		"startOfDay+PT9H30M":  time.Date(2021, 3, 17, 9, 30, 0, 0, time.UTC),
+		"P1D":                 time.Date(2021, 3, 18, 15, 4, 5, 0, time.UTC),
+		"-P7D":                time.Date(2021, 3, 10, 15, 4, 5, 0, time.UTC),
+		"-P1Y2M3DT4H5M6.5S":   time.Date(2020, 1, 14, 10, 58, 58, 500000000, time.UTC),
+		"now-P1W":             time.Date(2021, 3, 10, 15, 4, 5, 0, time.UTC),
+		"STARTOFMONTH+PT0.5S": time.Date(2021, 3, 1, 0, 0, 0, 500000000, time.UTC),
+	}
+	for value, exp := range cases {
+		got, ok := parseRelativeTime(value, now)
+		if !ok || !got.Equal(exp) {
+			t.Fatalf("%s: exp: %v, got: %v", value, exp, got)
+		}
+	}
+
+	// sunday belongs to the week started on monday
+	sunday := time.Date(2021, 3, 21, 8, 0, 0, 0, time.UTC)
+	if got, _ := parseRelativeTime("startOfWeek", sunday); !got.Equal(time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("exp: %v, got: %v", "2021-03-15", got)
+	}
+
+	for _, value := range []string{"", "yesterday", "now-", "now-7", "now-7x", "now7d", "P", "PT", "P1H", "PT1D", "now--1d", "P1DT1HT1M"} {
+		if got, ok := parseRelativeTime(value, now); ok {
+			t.Fatalf("%s: exp: invalid, got: %v", value, got)
+		}
+	}
+}
+
+func TestParseBetween(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{"c": "created_at", "a": "age", "n": "name"}
+	p.Metadata.FieldTypes = map[string]FieldType{"c": TypeTime, "a": TypeInt}
+	p.Metadata.TimeLocation = time.FixedZone("UTC+8", 8*3600)
+	p.Now = func() time.Time {
+		return time.Date(2021, 3, 17, 20, 0, 0, 0, time.UTC)
+	}
+
+	res := p.Parse(url.Values{"q": {"c__bt__[startOfDay-7d,now]|a__bt__18,65|n__bt__a,m"}})
+	exp := "created_at BETWEEN ? AND ? AND age BETWEEN ? AND ? AND name BETWEEN ? AND ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	loc := p.Metadata.TimeLocation
+	args := []interface{}{
+		time.Date(2021, 3, 11, 0, 0, 0, 0, loc),
+		time.Date(2021, 3, 18, 4, 0, 0, 0, loc),
+		int64(18), int64(65), "a", "m",
+	}
+	if len(res.WhereClause.Arguments) != len(args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+	for i, arg := range args {
+		if tm, ok := arg.(time.Time); ok {
+			if got, _ := res.WhereClause.Arguments[i].(time.Time); !got.Equal(tm) {
+				t.Fatalf("exp: %v, got: %v", tm, got)
+			}
+		} else if res.WhereClause.Arguments[i] != arg {
+			t.Fatalf("exp: %v, got: %v", arg, res.WhereClause.Arguments[i])
+		}
+	}
+	expKeys := map[string]interface{}{"age": int64(18), "age_2": int64(65), "name": "a", "name_2": "m"}
+	for key, value := range expKeys {
+		if res.WhereClause.ArgumentMap[key] != value {
+			t.Fatalf("exp: %v, got: %v", expKeys, res.WhereClause.ArgumentMap)
+		}
+	}
+
+	// numbered placeholders
+	p.Dialect = Postgres
+	res = p.Parse(url.Values{"q": {"a__bt__[18,65]|n__eq__x"}})
+	if exp := `"age" BETWEEN $1 AND $2 AND "name" = $3`; res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+
+	// exactly two bounds
+	for _, q := range []string{"a__bt__1", "a__bt__[1,2,3]", "c__bt__[now,soon]", "a__bt__[$null,1]"} {
+		_, err := p.ParseStrict(url.Values{"q": {q}})
+		if perr, ok := err.(*ParseError); !ok || perr.Errors[0].Reason != ReasonInvalidValue {
+			t.Fatalf("%s: exp: invalid value, got: %v", q, err)
+		}
+	}
+}
+
+func TestParseRelativeTimeValue(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{"c": "created_at"}
+	p.Metadata.FieldTypes = map[string]FieldType{"c": TypeTime}
+	p.Now = func() time.Time {
+		return time.Date(2021, 3, 17, 15, 0, 0, 0, time.UTC)
+	}
+
+	res, _ := p.ParseQuery("q=c__gte__now-7d|c__lt__today")
+	args := []interface{}{
+		time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC),
+		time.Date(2021, 3, 17, 0, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
@@ -0,0 +1,362 @@
+package djolar
+
+import "strings"
+
+// Syntax syntax of the boolean expressions of the q and h parameters
+type Syntax int
+
+const (
+	// SyntaxDjolar atoms written field__operator__value, joined with | and ||
+	SyntaxDjolar Syntax = iota
+	// SyntaxRSQL RSQL/FIQL expressions, eg., name==enix;age=gt=18
+	SyntaxRSQL
+)
+
+// RSQL support, enabled with Parser.Syntax = SyntaxRSQL.
+//
+// Grammar (AND binds tighter than OR):
+//
+// 	or         = and { ( "," | " or " ) and }
+// 	and        = constraint { ( ";" | " and " ) constraint }
+// 	constraint = "(" or ")" | comparison
+// 	comparison = selector operator ( value | "(" value { "," value } ")" )
+// 	value      = unreserved | '"' quoted '"' | "'" quoted "'"
+//
+// eg., q=name==enix;(age=gt=18,status=in=(a,b))
+// => name = ? AND (age > ? OR status IN (?))
+//
+// Operators are ==, !=, <, <=, >, >=, and any djolar operator written
+// =operator=, eg., =co=, with the FIQL aliases =le=, =ge=, =out=. With ==,
+// an unquoted value starting or ending with * is a LIKE pattern, eg.,
+// name==en* => name LIKE 'en%'. An unquoted $null is the null literal.
+//
+// As with the djolar syntax, a constraint which cannot be parsed is skipped
+// up to the next separator, and the other constraints are kept.
+
+// rsqlOperators RSQL operators mapped to djolar operators, other =op= are
+// looked up by name
+var rsqlOperators = map[string]string{
+	"==":    "eq",
+	"!=":    "ne",
+	"<":     "lt",
+	"<=":    "lte",
+	">":     "gt",
+	">=":    "gte",
+	"=le=":  "lte",
+	"=ge=":  "gte",
+	"=out=": "ni",
+}
+
+type rsqlParser struct {
+	src   string
+	pos   int
+	depth int
+	// text which could not be parsed, eg., unclosed parentheses
+	malformed []*exprNode
+}
+
+type rsqlValue struct {
+	text   string
+	quoted bool
+}
+
+// parseRSQL parse a RSQL expression into the expression tree of the atoms,
+// whose parts are the djolar field, operator and value
+func parseRSQL(src string) (*exprNode, []*exprNode) {
+	p := &rsqlParser{src: src}
+	if strings.TrimSpace(src) == "" {
+		return &exprNode{kind: exprAtom}, nil
+	}
+	node := p.parseOr()
+	if node == nil {
+		node = &exprNode{kind: exprAtom}
+	}
+	return node, p.malformed
+}
+
+func (p *rsqlParser) parseOr() *exprNode {
+	return p.parseList(exprOr, ',', "or", p.parseAnd)
+}
+
+func (p *rsqlParser) parseAnd() *exprNode {
+	return p.parseList(exprAnd, ';', "and", p.parseConstraint)
+}
+
+// parseList parse operands separated by the given symbol or keyword, the
+// operands which cannot be parsed are skipped, and a single operand is
+// returned as it is
+func (p *rsqlParser) parseList(kind exprKind, sep byte, keyword string, parse func() *exprNode) *exprNode {
+	node := &exprNode{kind: kind}
+	for {
+		if child := parse(); child != nil {
+			node.children = append(node.children, child)
+		}
+		p.skipGarbage()
+		if !p.separator(sep, keyword) {
+			break
+		}
+	}
+	switch len(node.children) {
+	case 0:
+		return nil
+	case 1:
+		return node.children[0]
+	}
+	return node
+}
+
+// separator consume the separator symbol, or the keyword surrounded by
+// spaces
+func (p *rsqlParser) separator(sep byte, keyword string) bool {
+	start := p.pos
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == sep {
+		p.pos++
+		return true
+	}
+	if p.keywordAt(p.pos) && strings.HasPrefix(p.src[p.pos:], keyword) {
+		p.pos += len(keyword)
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+func (p *rsqlParser) parseConstraint() *exprNode {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '(' {
+		start := p.pos
+		p.pos++
+		p.depth++
+		node := p.parseOr()
+		if p.skipSpace(); p.pos < len(p.src) && p.src[p.pos] == ')' {
+			p.pos++
+		} else {
+			p.malformed = append(p.malformed, &exprNode{kind: exprAtom, atom: "(", pos: start})
+		}
+		p.depth--
+		return node
+	}
+	return p.parseComparison()
+}
+
+func (p *rsqlParser) parseComparison() *exprNode {
+	start := p.pos
+	selector := p.scan(func(c byte) bool { return !isReserved(c) && !strings.ContainsRune("=!<>~", rune(c)) })
+	p.skipSpace()
+	op := p.scanOperator()
+	if selector == "" || op == "" {
+		p.skip(start)
+		return nil
+	}
+	p.skipSpace()
+	var values []rsqlValue
+	if p.pos < len(p.src) && p.src[p.pos] == '(' {
+		p.pos++
+		for {
+			p.skipSpace()
+			value, ok := p.scanValue()
+			if !ok {
+				p.skip(start)
+				return nil
+			}
+			values = append(values, value)
+			if p.skipSpace(); p.pos < len(p.src) && p.src[p.pos] == ',' {
+				p.pos++
+				continue
+			}
+			if p.pos < len(p.src) && p.src[p.pos] == ')' {
+				p.pos++
+				break
+			}
+			p.skip(start)
+			return nil
+		}
+	} else {
+		value, ok := p.scanValue()
+		if !ok {
+			p.skip(start)
+			return nil
+		}
+		values = append(values, value)
+	}
+
+	node := &exprNode{kind: exprAtom, atom: p.src[start:p.pos], pos: start}
+	parts, ok := rsqlParts(selector, op, values)
+	if !ok {
+		p.skip(start)
+		return nil
+	}
+	node.parts = parts
+	return node
+}
+
+// rsqlParts translate a comparison to the field, operator and value of a
+// djolar atom
+func rsqlParts(selector, op string, values []rsqlValue) (*atomParts, bool) {
+	name, ok := rsqlOperators[op]
+	if !ok {
+		name = strings.Trim(op, "=")
+	}
+	if name == "in" || name == "ni" || name == "bt" {
+		items := make([]string, len(values))
+		for i, value := range values {
+			items[i] = value.atomValue()
+		}
+		return &atomParts{field: selector, op: name, value: "[" + strings.Join(items, ",") + "]"}, true
+	}
+	if len(values) != 1 {
+		return nil, false
+	}
+	value := values[0]
+	if op == "==" && !value.quoted && value.text != NullLiteral {
+		starts, ends := strings.HasPrefix(value.text, "*"), strings.HasSuffix(value.text, "*")
+		switch {
+		case starts && ends && len(value.text) > 1:
+			name, value.text = "co", value.text[1:len(value.text)-1]
+		case starts:
+			name, value.text = "ew", value.text[1:]
+		case ends:
+			name, value.text = "sw", value.text[:len(value.text)-1]
+		}
+	}
+	return &atomParts{field: selector, op: name, value: value.atomValue()}, true
+}
+
+// atomValue write the value with the quoting of the atoms
+func (v rsqlValue) atomValue() string {
+	if !v.quoted && v.text == NullLiteral {
+		return v.text
+	}
+	return `"` + escapeValue(v.text) + `"`
+}
+
+// scanOperator read ==, !=, <, <=, >, >= or =name=
+func (p *rsqlParser) scanOperator() string {
+	rest := p.src[p.pos:]
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(rest, op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	if strings.HasPrefix(rest, "=") {
+		if i := strings.IndexByte(rest[1:], '='); i > 0 && isWord(rest[1:i+1]) {
+			p.pos += i + 2
+			return rest[:i+2]
+		}
+	}
+	return ""
+}
+
+// scanValue read an unreserved or a quoted value, quoted values may escape
+// any character with `\`
+func (p *rsqlParser) scanValue() (rsqlValue, bool) {
+	if p.pos >= len(p.src) {
+		return rsqlValue{}, false
+	}
+	quote := p.src[p.pos]
+	if quote != '"' && quote != '\'' {
+		text := p.scan(func(c byte) bool { return !isReserved(c) })
+		return rsqlValue{text: text}, text != ""
+	}
+	var b strings.Builder
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch c := p.src[i]; {
+		case c == '\\' && i+1 < len(p.src):
+			i++
+			b.WriteByte(p.src[i])
+		case c == quote:
+			p.pos = i + 1
+			return rsqlValue{text: b.String(), quoted: true}, true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return rsqlValue{}, false
+}
+
+func (p *rsqlParser) scan(accept func(c byte) bool) string {
+	start := p.pos
+	for p.pos < len(p.src) && accept(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *rsqlParser) skipSpace() {
+	for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// skip mark the constraint starting at start as malformed, up to the next
+// separator which is neither quoted nor nested in parentheses
+func (p *rsqlParser) skip(start int) {
+	p.pos = start
+	nested := 0
+	var quote byte
+loop:
+	for ; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				p.pos++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			nested++
+		case c == ')':
+			if nested > 0 {
+				nested--
+			} else if p.depth > 0 {
+				break loop
+			}
+		case nested == 0 && (c == ';' || c == ',' || p.keywordAt(p.pos)):
+			break loop
+		}
+	}
+	atom := strings.TrimRight(p.src[start:p.pos], " \t\r\n")
+	p.malformed = append(p.malformed, &exprNode{kind: exprAtom, atom: atom, pos: start})
+}
+
+// skipGarbage drop anything following a constraint up to the next
+// separator, eg., the `x` in `(a==1)x;b==2`
+func (p *rsqlParser) skipGarbage() {
+	start := p.pos
+	p.skipSpace()
+	if p.pos >= len(p.src) || strings.IndexByte(";,", p.src[p.pos]) >= 0 ||
+		(p.src[p.pos] == ')' && p.depth > 0) || p.keywordAt(p.pos) {
+		p.pos = start
+		return
+	}
+	p.skip(p.pos)
+}
+
+// keywordAt check if a keyword separator starts at i, eg., ` and `
+func (p *rsqlParser) keywordAt(i int) bool {
+	if i == 0 || !isSpace(p.src[i-1]) {
+		return false
+	}
+	rest := p.src[i:]
+	for _, keyword := range []string{"and", "or"} {
+		if strings.HasPrefix(rest, keyword) && len(rest) > len(keyword) &&
+			(isSpace(rest[len(keyword)]) || rest[len(keyword)] == '(') {
+			return true
+		}
+	}
+	return false
+}
+
+// isReserved characters which cannot appear in unquoted RSQL values
+func isReserved(c byte) bool {
+	return isSpace(c) || strings.IndexByte(`"'();,`, c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,141 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestRSQL(t *testing.T) {
+	p := NewParser()
+	p.Syntax = SyntaxRSQL
+	p.Metadata.QueryMapping = map[string]string{
+		"name":    "name",
+		"age":     "age",
+		"status":  "status",
+		"deleted": "deleted_at",
+	}
+	p.Metadata.FieldTypes = map[string]FieldType{"age": TypeInt}
+
+	cases := []struct {
+		q     string
+		where string
+		args  []interface{}
+	}{
+		{"name==enix;age=gt=18,status=in=(a,b)", "name = ? AND age > ? OR status IN (?)", []interface{}{"enix", int64(18), []string{"a", "b"}}},
+		{"name==enix;(age=gt=18,status=in=(a,b))", "name = ? AND (age > ? OR status IN (?))", []interface{}{"enix", int64(18), []string{"a", "b"}}},
+		{"age>=18 and age<65 or name!=x", "age >= ? AND age < ? OR name <> ?", []interface{}{int64(18), int64(65), "x"}},
+		{"age=le=9;age=ge=1;status=out=(x)", "age <= ? AND age >= ? AND status NOT IN (?)", []interface{}{int64(9), int64(1), []string{"x"}}},
+		{`name=="a,b;c";status=in=('x)', "y\"z")`, "name = ? AND status IN (?)", []interface{}{"a,b;c", []string{"x)", `y"z`}}},
+		{"name==en*;status==*a*;name==*x", "name LIKE ? ESCAPE '!' AND status LIKE ? ESCAPE '!' AND name LIKE ? ESCAPE '!'", []interface{}{"en%", "%a%", "%x"}},
+		{"name=='en*'", "name = ?", []interface{}{"en*"}},
+		{"name=co=a_b", "name LIKE ? ESCAPE '!'", []interface{}{"%a!_b%"}},
+		{"age=bt=(1,9)", "age BETWEEN ? AND ?", []interface{}{int64(1), int64(9)}},
+		{"deleted=isnull=true;name==$null;status=isnull=false", "deleted_at IS NULL AND name IS NULL AND NOT (status IS NULL)", []interface{}{}},
+		{"name=='$null'", "name = ?", []interface{}{"$null"}},
+		{"age=gt=18;name==", "age > ?", []interface{}{int64(18)}},
+	}
+	for _, c := range cases {
+		res, _ := p.ParseQuery("q=" + url.QueryEscape(c.q))
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("%s exp: %v, got: %v", c.q, c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("%s exp: %v, got: %v", c.q, c.args, res.WhereClause.Arguments)
+		}
+	}
+
+	// same result as the djolar syntax
+	exp, _ := (&Parser{Metadata: p.Metadata}).ParseQuery("q=" + url.QueryEscape("name__eq__enix|(age__gt__18||status__in__[a,b])") + "&h=age__sum__gt__1")
+	res, _ := p.ParseQuery("q=" + url.QueryEscape("name==enix;(age=gt=18,status=in=(a,b))") + "&h=age__sum=gt=1")
+	if !reflect.DeepEqual(res, exp) {
+		t.Fatalf("exp: %v, got: %v", exp, res)
+	}
+}
+
+func TestRSQLErrors(t *testing.T) {
+	p := NewParser()
+	p.Syntax = SyntaxRSQL
+	p.Metadata.QueryMapping = map[string]string{"age": "age"}
+
+	cases := []struct {
+		q   string
+		err *AtomError
+	}{
+		{"user.name==x", &AtomError{Param: "q", Atom: "user.name==x", Field: "user.name", Operator: "eq", Reason: ReasonUnknownField}},
+		{"age=zz=1", &AtomError{Param: "q", Atom: "age=zz=1", Field: "age", Operator: "zz", Reason: ReasonUnknownOperator}},
+		{"age==1;age~1", &AtomError{Param: "q", Position: 7, Atom: "age~1", Reason: ReasonMalformed}},
+		{"(age==1", &AtomError{Param: "q", Atom: "(", Reason: ReasonMalformed}},
+		{"age==(1,2)", &AtomError{Param: "q", Atom: "age==(1,2)", Reason: ReasonMalformed}},
+		{"age==1)", &AtomError{Param: "q", Position: 6, Atom: ")", Reason: ReasonMalformed}},
+		{`age=="1`, &AtomError{Param: "q", Atom: `age=="1`, Reason: ReasonMalformed}},
+	}
+	for _, c := range cases {
+		_, err := p.ParseStrict(url.Values{"q": {c.q}})
+		var perr *ParseError
+		if !errors.As(err, &perr) || len(perr.Errors) != 1 || !reflect.DeepEqual(perr.Errors[0], c.err) {
+			t.Fatalf("%s exp: %v, got: %v", c.q, c.err, err)
+		}
+	}
+}
+
+func TestRSQLRecovery(t *testing.T) {
+	p := NewParser()
+	p.Syntax = SyntaxRSQL
+	p.Metadata.QueryMapping = map[string]string{"name": "name", "tenant": "tenant_id", "age": "age"}
+
+	// constraints which cannot be parsed are skipped, the others are kept
+	cases := []struct {
+		q     string
+		where string
+		atoms []string
+	}{
+		{"name==;tenant==5", "tenant_id = ?", []string{"name=="}},
+		{"tenant==5;name=in=(a,;b));age>1", "tenant_id = ? AND age > ?", []string{"name=in=(a,;b))"}},
+		{`tenant==5;(name=="x,age>1);age<9`, "tenant_id = ?", []string{`name=="x,age>1);age<9`, "("}},
+		{"tenant==5 and (age~1 or age>1) and name==x", "tenant_id = ? AND age > ? AND name = ?", []string{"age~1"}},
+		{"(tenant==5)x;age>1", "tenant_id = ? AND age > ?", []string{"x"}},
+		{"tenant==5;age>1)", "tenant_id = ? AND age > ?", []string{")"}},
+	}
+	for _, c := range cases {
+		res, _ := p.ParseQuery("q=" + url.QueryEscape(c.q))
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("%s exp: %v, got: %v", c.q, c.where, res.WhereClause.Where)
+		}
+		_, err := p.ParseStrict(url.Values{"q": {c.q}})
+		var perr *ParseError
+		if !errors.As(err, &perr) || len(perr.Errors) != len(c.atoms) {
+			t.Fatalf("%s exp: %v, got: %v", c.q, c.atoms, err)
+		}
+		for i, atom := range c.atoms {
+			if perr.Errors[i].Atom != atom {
+				t.Fatalf("%s exp: %v, got: %v", c.q, atom, perr.Errors[i].Atom)
+			}
+		}
+	}
+}
+
+func TestRSQLRawSemicolon(t *testing.T) {
+	p := NewParser()
+	p.Syntax = SyntaxRSQL
+	p.Metadata.QueryMapping = map[string]string{"name": "name", "age": "age"}
+	p.Metadata.FieldTypes = map[string]FieldType{"age": TypeInt}
+
+	// ; is not escaped by most clients, url.ParseQuery would drop q
+	exp := "name = ? AND (age > ? OR name = ?)"
+	res, err := p.ParseQuery("q=name==enix;(age=gt=18,name=='a;b')&s=-age")
+	if err != nil || res.WhereClause.Where != exp || res.OrderByClause != "age DESC" {
+		t.Fatalf("exp: %v, got: %v, %v", exp, res, err)
+	}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, []interface{}{"enix", int64(18), "a;b"}) {
+		t.Fatalf("exp: %v, got: %v", []interface{}{"enix", int64(18), "a;b"}, res.WhereClause.Arguments)
+	}
+	res, err = p.ParseURI("/users?q=name==enix;age=gt=18")
+	if err != nil || res.WhereClause.Where != "name = ? AND age > ?" {
+		t.Fatalf("exp: %v, got: %v, %v", "name = ? AND age > ?", res, err)
+	}
+	if _, err := p.ParseQuery("q=name==%zz"); err == nil {
+		t.Fatalf("exp: error, got: nil")
+	}
+}
@@ -0,0 +1,95 @@
+package djolar
+
+import (
+	"errors"
+	"strings"
+)
+
+// Dialect dialect of the parser which produced the result, nil if none
+func (r *ParseResult) Dialect() Dialect {
+	return r.dialect
+}
+
+// Statement render a complete SELECT statement on the table for
+// database/sql, with the arguments of the WHERE and HAVING clauses in
+// order, eg.,
+//
+//	parser.Dialect = djolar.Postgres
+//	res, _ := parser.ParseQuery("q=a__gt__18&s=-a&limit=20")
+//	stmt, args := res.Statement("users")
+//	// SELECT * FROM "users" WHERE "age" > $1 ORDER BY "age" DESC LIMIT 20
+//	rows, err := db.Query(stmt, args...)
+//
+// Identifiers, placeholders and the page window follow the dialect of the
+// parser.
+func (r *ParseResult) Statement(table string) (string, []interface{}) {
+	args := make([]interface{}, 0, len(r.WhereClause.Arguments)+len(r.HavingClause.Arguments))
+	args = append(args, r.WhereClause.Arguments...)
+	args = append(args, r.HavingClause.Arguments...)
+	return r.statement(table), args
+}
+
+// NamedStatement render a complete SELECT statement on the table with
+// named parameters for sqlx, eg.,
+//
+//	parser.GetPlaceHolder = djolar.NamedPlaceHolder
+//	res, _ := parser.ParseQuery("q=a__gt__18")
+//	stmt, args, err := res.NamedStatement("users")
+//	// SELECT * FROM users WHERE age > :age
+//	rows, err := db.NamedQuery(stmt, args)
+//
+// The arguments are read from the ArgumentMap of the WHERE and HAVING
+// clauses, raw conditions of the meta data must use named parameters too,
+// their values have to be added to the returned map. Dialects number the
+// placeholders instead of calling GetPlaceHolder, so the parser must not
+// have a Dialect.
+func (r *ParseResult) NamedStatement(table string) (string, map[string]interface{}, error) {
+	if r.dialect != nil {
+		return "", nil, errors.New("djolar: named statements need a parser without Dialect, use Statement")
+	}
+	args := make(map[string]interface{}, len(r.WhereClause.ArgumentMap)+len(r.HavingClause.ArgumentMap))
+	for key, value := range r.WhereClause.ArgumentMap {
+		args[key] = value
+	}
+	for key, value := range r.HavingClause.ArgumentMap {
+		args[key] = value
+	}
+	return r.statement(table), args, nil
+}
+
+func (r *ParseResult) statement(table string) string {
+	selectClause := r.SelectClause
+	if len(selectClause) == 0 {
+		selectClause = "*"
+	}
+	stmt := []string{"SELECT", selectClause, "FROM", quoteIdentifier(r.dialect, table)}
+	if len(r.WhereClause.Where) > 0 {
+		stmt = append(stmt, "WHERE", r.WhereClause.Where)
+	}
+	if len(r.GroupByClause) > 0 {
+		stmt = append(stmt, "GROUP BY", r.GroupByClause)
+	}
+	if len(r.HavingClause.Where) > 0 {
+		stmt = append(stmt, "HAVING", r.HavingClause.Where)
+	}
+	if len(r.OrderByClause) > 0 {
+		stmt = append(stmt, "ORDER BY", r.OrderByClause)
+	}
+
+	var limit string
+	if r.dialect != nil {
+		limit = r.dialect.LimitClause(r.Limit, r.Offset, len(r.OrderByClause) > 0)
+	} else {
+		limit = limitClause(r.Limit, r.Offset, "")
+	}
+	if len(limit) > 0 {
+		stmt = append(stmt, limit)
+	}
+	return strings.Join(stmt, " ")
+}
+
+// NamedPlaceHolder placeholder for named parameters, eg., :age, matching
+// the keys of the ArgumentMap built with the default GetArgMapKey
+func NamedPlaceHolder(md *MetaData, fieldname string) string {
+	return ":" + defaultArgMapFunc(md, fieldname)
+}
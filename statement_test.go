@@ -0,0 +1,88 @@
+package djolar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatement(t *testing.T) {
+	query := "q=n__eq__bob|a__in__[1,2]&f=n,a__sum&g=n&h=a__sum__gt__10&s=-n&limit=20&offset=40"
+
+	cases := []struct {
+		dialect Dialect
+		exp     string
+	}{
+		{nil, "SELECT name,SUM(age) AS a__sum FROM public.users WHERE name = ? AND age IN (?) GROUP BY name HAVING SUM(age) > ? ORDER BY name DESC LIMIT 20 OFFSET 40"},
+		{Postgres, `SELECT "name",SUM("age") AS "a__sum" FROM "public"."users" WHERE "name" = $1 AND "age" IN ($2, $3) GROUP BY "name" HAVING SUM("age") > $4 ORDER BY "name" DESC LIMIT 20 OFFSET 40`},
+		{MySQL, "SELECT `name`,SUM(`age`) AS `a__sum` FROM `public`.`users` WHERE `name` = ? AND `age` IN (?, ?) GROUP BY `name` HAVING SUM(`age`) > ? ORDER BY `name` DESC LIMIT 20 OFFSET 40"},
+		{SQLServer, "SELECT [name],SUM([age]) AS [a__sum] FROM [public].[users] WHERE [name] = @p1 AND [age] IN (@p2, @p3) GROUP BY [name] HAVING SUM([age]) > @p4 ORDER BY [name] DESC OFFSET 40 ROWS FETCH NEXT 20 ROWS ONLY"},
+	}
+	for _, c := range cases {
+		p := NewParser()
+		p.Dialect = c.dialect
+		p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+		res, _ := p.ParseQuery(query)
+
+		stmt, args := res.Statement("public.users")
+		if stmt != c.exp {
+			t.Fatalf("exp: %v, got: %v", c.exp, stmt)
+		}
+		exp := []interface{}{"bob", "1", "2", "10"}
+		if c.dialect == nil {
+			exp = []interface{}{"bob", []string{"1", "2"}, "10"}
+		}
+		if !reflect.DeepEqual(args, exp) {
+			t.Fatalf("exp: %v, got: %v", exp, args)
+		}
+	}
+}
+
+func TestStatementPage(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		query   string
+		exp     string
+	}{
+		{nil, "", "SELECT * FROM users"},
+		{nil, "offset=10", "SELECT * FROM users OFFSET 10"},
+		{MySQL, "offset=10", "SELECT * FROM `users` LIMIT 18446744073709551615 OFFSET 10"},
+		{SQLite, "offset=10", `SELECT * FROM "users" LIMIT -1 OFFSET 10`},
+		{SQLServer, "limit=5", "SELECT * FROM [users] ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY"},
+		{SQLServer, "", "SELECT * FROM [users]"},
+	}
+	for _, c := range cases {
+		p := NewParser()
+		p.Dialect = c.dialect
+		res, _ := p.ParseQuery(c.query)
+		if stmt, _ := res.Statement("users"); stmt != c.exp {
+			t.Fatalf("exp: %v, got: %v", c.exp, stmt)
+		}
+	}
+}
+
+func TestNamedStatement(t *testing.T) {
+	p := NewParser()
+	p.GetPlaceHolder = NamedPlaceHolder
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+	res, _ := p.ParseQuery("q=n__eq__bob|a__gt__18&f=n&g=n&h=a__sum__gt__10&limit=5")
+
+	stmt, args, err := res.NamedStatement("users")
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	exp := "SELECT name FROM users WHERE name = :name AND age > :age GROUP BY name HAVING SUM(age) > :a__sum LIMIT 5"
+	if stmt != exp {
+		t.Fatalf("exp: %v, got: %v", exp, stmt)
+	}
+	expArgs := map[string]interface{}{"name": "bob", "age": "18", "a__sum": "10"}
+	if !reflect.DeepEqual(args, expArgs) {
+		t.Fatalf("exp: %v, got: %v", expArgs, args)
+	}
+
+	// numbered placeholders cannot be bound by name
+	p.Dialect = Postgres
+	res, _ = p.ParseQuery("q=n__eq__bob")
+	if _, _, err := res.NamedStatement("users"); err == nil {
+		t.Fatalf("exp: err with a dialect, got: nil")
+	}
+}
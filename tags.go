@@ -0,0 +1,191 @@
+package djolar
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TagName struct tag read by NewMetaData
+const TagName = "djolar"
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(UUID{})
+)
+
+// NewMetaData build MetaData from the `djolar` struct tags of a model.
+//
+// Tag format is the query field alias followed by options:
+//
+//	type User struct {
+//		ID        uint      `djolar:"id,ops=eq|in,sortable"`
+//		Name      string    `djolar:"n,ops=eq|co,sortable,groupable"`
+//		Age       int       `djolar:"a,sortable,groupable"`
+//		CreatedAt time.Time `djolar:",sortable" gorm:"column:created"`
+//		Password  string
+//	}
+//
+// Options:
+//
+//	ops=op1|op2  operators accepted by the field, any operator if omitted,
+//	             builtin operators only, see DefaultOperators
+//	sortable     the field can be used in the s parameter
+//	groupable    the field can be used in the g parameter
+//	aggregatable the field can be aggregated in the f and h parameters
+//	type=xxx     field type, eg., int, inferred from the Go type if omitted
+//
+// The column is read from the gorm `column` tag, or derived from the field
+// name with the gorm naming convention (eg., CreatedAt => created_at).
+// An empty alias use the column name. Fields without tag, or tagged `-`,
+// are not exposed. Embedded structs such as gorm.Model are walked.
+func NewMetaData(model interface{}) (MetaData, error) {
+	md := MetaData{
+		QueryMapping:       map[string]string{},
+		DefaultSearch:      map[string]interface{}{},
+		ForceSearch:        map[string]interface{}{},
+		DefaultOrderBy:     []string{},
+		ForceOrderBy:       []string{},
+		FieldTypes:         map[string]FieldType{},
+		AllowedOperators:   map[string][]string{},
+		SortableFields:     []string{},
+		GroupableFields:    []string{},
+		AggregatableFields: []string{},
+	}
+
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return md, fmt.Errorf("djolar: model must be a struct, got %v", t)
+	}
+
+	if err := collectFields(t, &md); err != nil {
+		return md, err
+	}
+	return md, nil
+}
+
+func collectFields(t reflect.Type, md *MetaData) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup(TagName)
+		if tag == "-" {
+			continue
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && !tagged && ft.Kind() == reflect.Struct {
+			if err := collectFields(ft, md); err != nil {
+				return err
+			}
+			continue
+		}
+		if !tagged || sf.PkgPath != "" {
+			continue
+		}
+
+		column := gormColumn(sf)
+		opts := strings.Split(tag, ",")
+		alias := strings.TrimSpace(opts[0])
+		if alias == "" {
+			alias = column
+		}
+		if _, ok := md.QueryMapping[alias]; ok {
+			return fmt.Errorf("djolar: duplicate query field %q on %s.%s", alias, t.Name(), sf.Name)
+		}
+		md.QueryMapping[alias] = column
+		if fieldType, ok := inferFieldType(ft); ok {
+			md.FieldTypes[alias] = fieldType
+		}
+
+		for _, opt := range opts[1:] {
+			opt = strings.TrimSpace(opt)
+			switch {
+			case opt == "sortable":
+				md.SortableFields = append(md.SortableFields, alias)
+			case opt == "groupable":
+				md.GroupableFields = append(md.GroupableFields, alias)
+			case opt == "aggregatable":
+				md.AggregatableFields = append(md.AggregatableFields, alias)
+			case strings.HasPrefix(opt, "ops="):
+				ops := strings.Split(opt[len("ops="):], "|")
+				for _, op := range ops {
+					if _, ok := operators[op]; !ok {
+						return fmt.Errorf("djolar: unknown operator %q on %s.%s", op, t.Name(), sf.Name)
+					}
+				}
+				md.AllowedOperators[alias] = ops
+			case strings.HasPrefix(opt, "type="):
+				fieldType := FieldType(opt[len("type="):])
+				if !fieldType.valid() {
+					return fmt.Errorf("djolar: unknown field type %q on %s.%s", fieldType, t.Name(), sf.Name)
+				}
+				md.FieldTypes[alias] = fieldType
+			case opt == "":
+			default:
+				return fmt.Errorf("djolar: unknown tag option %q on %s.%s", opt, t.Name(), sf.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// gormColumn column name of a struct field, following gorm conventions
+func gormColumn(sf reflect.StructField) string {
+	for _, setting := range strings.Split(sf.Tag.Get("gorm"), ";") {
+		kv := strings.SplitN(setting, ":", 2)
+		if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "column") {
+			return strings.TrimSpace(kv[1])
+		}
+	}
+	return toDBName(sf.Name)
+}
+
+// toDBName convert a Go field name to snake case, keeping initialisms
+// together, eg., UserID => user_id, HTTPServer => http_server
+func toDBName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func inferFieldType(t reflect.Type) (FieldType, bool) {
+	switch t {
+	case timeType:
+		return TypeTime, true
+	case uuidType:
+		return TypeUUID, true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInt, true
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat, true
+	case reflect.Bool:
+		return TypeBool, true
+	case reflect.String:
+		return TypeString, true
+	}
+	return "", false
+}
@@ -0,0 +1,166 @@
+package djolar
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testModel struct {
+	ID        uint `gorm:"primarykey" djolar:"id,ops=eq|in,sortable"`
+	CreatedAt time.Time
+	UpdatedAt *time.Time `djolar:",sortable"`
+}
+
+type testUser struct {
+	testModel
+	Name     string  `djolar:"n,ops=eq|co,sortable,groupable"`
+	Age      int     `djolar:"a,sortable,groupable,aggregatable"`
+	Score    float64 `djolar:"s,aggregatable" gorm:"column:total_score"`
+	Verified bool    `djolar:"v"`
+	OrgUUID  string  `djolar:"o,type=uuid"`
+	Password string
+	Secret   string `djolar:"-"`
+	HTTPHost string `djolar:"h"`
+}
+
+func TestNewMetaData(t *testing.T) {
+	md, err := NewMetaData(&testUser{})
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+
+	expMapping := map[string]string{
+		"id":         "id",
+		"updated_at": "updated_at",
+		"n":          "name",
+		"a":          "age",
+		"s":          "total_score",
+		"v":          "verified",
+		"o":          "org_uuid",
+		"h":          "http_host",
+	}
+	if !reflect.DeepEqual(md.QueryMapping, expMapping) {
+		t.Fatalf("exp: %v, got: %v", expMapping, md.QueryMapping)
+	}
+
+	expTypes := map[string]FieldType{
+		"id":         TypeInt,
+		"updated_at": TypeTime,
+		"n":          TypeString,
+		"a":          TypeInt,
+		"s":          TypeFloat,
+		"v":          TypeBool,
+		"o":          TypeUUID,
+		"h":          TypeString,
+	}
+	if !reflect.DeepEqual(md.FieldTypes, expTypes) {
+		t.Fatalf("exp: %v, got: %v", expTypes, md.FieldTypes)
+	}
+
+	expOps := map[string][]string{
+		"id": {"eq", "in"},
+		"n":  {"eq", "co"},
+	}
+	if !reflect.DeepEqual(md.AllowedOperators, expOps) {
+		t.Fatalf("exp: %v, got: %v", expOps, md.AllowedOperators)
+	}
+
+	expSortable := []string{"id", "updated_at", "n", "a"}
+	if !reflect.DeepEqual(md.SortableFields, expSortable) {
+		t.Fatalf("exp: %v, got: %v", expSortable, md.SortableFields)
+	}
+	expGroupable := []string{"n", "a"}
+	if !reflect.DeepEqual(md.GroupableFields, expGroupable) {
+		t.Fatalf("exp: %v, got: %v", expGroupable, md.GroupableFields)
+	}
+	expAggregatable := []string{"a", "s"}
+	if !reflect.DeepEqual(md.AggregatableFields, expAggregatable) {
+		t.Fatalf("exp: %v, got: %v", expAggregatable, md.AggregatableFields)
+	}
+}
+
+func TestParseWithTaggedMetaData(t *testing.T) {
+	md, _ := NewMetaData(testUser{})
+	p := NewParser()
+	p.Metadata = md
+
+	res, _ := p.ParseQuery("q=n__co__en|n__gt__a|a__gt__18&s=-a,s&g=n,v")
+
+	exp := "name LIKE ? ESCAPE '!' AND age > ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"%en%", int64(18)}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+	if res.OrderByClause != "age DESC" {
+		t.Fatalf("exp: %v, got: %v", "age DESC", res.OrderByClause)
+	}
+	if res.GroupByClause != "name" {
+		t.Fatalf("exp: %v, got: %v", "name", res.GroupByClause)
+	}
+
+	qv := map[string][]string{"s": {"s"}}
+	_, err := p.ParseStrict(qv)
+	perr, ok := err.(*ParseError)
+	if !ok || len(perr.Errors) != 1 || perr.Errors[0].Reason != ReasonFieldNotAllowed {
+		t.Fatalf("exp: field not allowed, got: %v", err)
+	}
+}
+
+func TestNewMetaDataErrors(t *testing.T) {
+	if _, err := NewMetaData(1); err == nil {
+		t.Fatalf("exp: err for non struct, got: nil")
+	}
+
+	type duplicated struct {
+		A int `djolar:"a"`
+		B int `djolar:"a"`
+	}
+	if _, err := NewMetaData(duplicated{}); err == nil {
+		t.Fatalf("exp: err for duplicated alias, got: nil")
+	}
+
+	type unknownOption struct {
+		A int `djolar:"a,searchable"`
+	}
+	if _, err := NewMetaData(unknownOption{}); err == nil {
+		t.Fatalf("exp: err for unknown option, got: nil")
+	}
+
+	type unknownType struct {
+		N string `djolar:"n,type=strin,ops=eq|contains"`
+	}
+	_, err := NewMetaData(unknownType{})
+	exp := `djolar: unknown field type "strin" on unknownType.N`
+	if err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, err)
+	}
+
+	type unknownOperator struct {
+		N string `djolar:"n,ops=eq|contains"`
+	}
+	_, err = NewMetaData(unknownOperator{})
+	exp = `djolar: unknown operator "contains" on unknownOperator.N`
+	if err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, err)
+	}
+}
+
+func TestToDBName(t *testing.T) {
+	cases := map[string]string{
+		"ID":         "id",
+		"UserID":     "user_id",
+		"CreatedAt":  "created_at",
+		"HTTPServer": "http_server",
+		"Field1Name": "field1_name",
+		"name":       "name",
+	}
+	for name, exp := range cases {
+		if got := toDBName(name); got != exp {
+			t.Fatalf("exp: %v, got: %v", exp, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package djolar
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType value type of a query field, arguments of the field are
+// converted to the corresponding Go type
+type FieldType string
+
+const (
+	// TypeString keep the value as string, the default
+	TypeString FieldType = "string"
+	// TypeInt convert the value to int64
+	TypeInt FieldType = "int"
+	// TypeFloat convert the value to float64
+	TypeFloat FieldType = "float"
+	// TypeBool convert the value to bool, see strconv.ParseBool
+	TypeBool FieldType = "bool"
+	// TypeTime convert the value to time.Time, see TimeLayouts
+	TypeTime FieldType = "time"
+	// TypeUUID convert the value to UUID
+	TypeUUID FieldType = "uuid"
+)
+
+// valid check if the type is one of the FieldType constants
+func (t FieldType) valid() bool {
+	switch t {
+	case TypeString, TypeInt, TypeFloat, TypeBool, TypeTime, TypeUUID:
+		return true
+	}
+	return false
+}
+
+// TimeLayouts layouts accepted for TypeTime values, tried in order
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// UUID a RFC 4122 UUID, bound to SQL as its canonical string
+type UUID [16]byte
+
+// ParseUUID parse a UUID in the canonical 8-4-4-4-12 form, or as 32 hex digits
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	h := s
+	if len(s) == 36 {
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return u, fmt.Errorf("djolar: invalid UUID %q", s)
+		}
+		h = s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+	if len(h) != 32 {
+		return u, fmt.Errorf("djolar: invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("djolar: invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+// String return the canonical lower case form of the UUID
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// Value implement driver.Valuer
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// convertArgument convert the argument built by an ArgumentHandler to the
+// field type. Lists are converted element by element, other values which are
+// not strings are returned unchanged.
+func convertArgument(t FieldType, arg interface{}, loc *time.Location, now time.Time) (interface{}, error) {
+	switch v := arg.(type) {
+	case string:
+		return convertValue(t, v, loc, now)
+	case []string:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			value, err := convertValue(t, item, loc, now)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return arg, nil
+}
+
+func convertValue(t FieldType, value string, loc *time.Location, now time.Time) (interface{}, error) {
+	switch t {
+	case TypeString, "":
+		return value, nil
+	case TypeInt:
+		return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	case TypeFloat:
+		return strconv.ParseFloat(strings.TrimSpace(value), 64)
+	case TypeBool:
+		return strconv.ParseBool(strings.TrimSpace(value))
+	case TypeTime:
+		return parseTime(strings.TrimSpace(value), loc, now)
+	case TypeUUID:
+		return ParseUUID(strings.TrimSpace(value))
+	}
+	return nil, fmt.Errorf("djolar: unknown field type %q", t)
+}
+
+func parseTime(value string, loc *time.Location, now time.Time) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range TimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok := parseRelativeTime(value, now.In(loc)); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("djolar: invalid time %q", value)
+}
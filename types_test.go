@@ -0,0 +1,150 @@
+package djolar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTypedArguments(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{
+			"a": "age",
+			"s": "score",
+			"v": "verified",
+			"c": "created_at",
+			"u": "uuid",
+			"n": "name",
+		},
+		FieldTypes: map[string]FieldType{
+			"a": TypeInt,
+			"s": TypeFloat,
+			"v": TypeBool,
+			"c": TypeTime,
+			"u": TypeUUID,
+			"n": TypeString,
+		},
+	}
+
+	res, err := p.ParseQuery("q=a__gte__18|s__lt__9.5|v__eq__true|c__gte__2021-01-11%2000%3A00|u__eq__6BA7B810-9DAD-11D1-80B4-00C04FD430C8|n__co__12")
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+
+	uuid, _ := ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	args := []interface{}{
+		int64(18),
+		9.5,
+		true,
+		time.Date(2021, 1, 11, 0, 0, 0, 0, time.UTC),
+		uuid,
+		"%12%",
+	}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseTypedListArguments(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"a": "age"},
+		FieldTypes:   map[string]FieldType{"a": TypeInt},
+	}
+
+	res, _ := p.ParseQuery("q=a__in__[1,2,3]|a__ni__[4]")
+
+	args := []interface{}{
+		[]interface{}{int64(1), int64(2), int64(3)},
+		[]interface{}{int64(4)},
+	}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseTimeWithLocation(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{"c": "created_at"},
+		FieldTypes:   map[string]FieldType{"c": TypeTime},
+	}
+	loc := time.FixedZone("UTC+8", 8*3600)
+	p.Metadata.TimeLocation = loc
+
+	res, _ := p.ParseQuery("q=c__gte__2021-01-11|c__lt__2021-01-12T00%3A00%3A00Z")
+
+	args := []interface{}{
+		time.Date(2021, 1, 11, 0, 0, 0, 0, loc),
+		time.Date(2021, 1, 12, 0, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+}
+
+func TestParseInvalidTypedArguments(t *testing.T) {
+	p := NewParser()
+	p.Metadata = MetaData{
+		QueryMapping: map[string]string{
+			"a": "age",
+			"s": "score",
+			"v": "verified",
+			"c": "created_at",
+			"u": "uuid",
+			"n": "name",
+		},
+		FieldTypes: map[string]FieldType{
+			"a": TypeInt,
+			"s": TypeFloat,
+			"v": TypeBool,
+			"c": TypeTime,
+			"u": TypeUUID,
+			"n": TypeString,
+		},
+	}
+
+	res, _ := p.ParseQuery("q=a__gt__abc|s__eq__x|v__eq__maybe|c__gt__yesterday|u__eq__123|a__in__[1,b]|n__eq__x")
+	exp := "name = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+
+	qv, _ := url.ParseQuery("q=a__gt__abc|a__in__[1,b]|a__eq__2")
+	_, err := p.ParseStrict(qv)
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("exp: *ParseError, got: %v", err)
+	}
+	expErrs := []*AtomError{
+		{Param: "q", Position: 0, Atom: "a__gt__abc", Field: "a", Operator: "gt", Reason: ReasonInvalidValue},
+		{Param: "q", Position: 11, Atom: "a__in__[1,b]", Field: "a", Operator: "in", Reason: ReasonInvalidValue},
+	}
+	if !reflect.DeepEqual(perr.Errors, expErrs) {
+		t.Fatalf("exp: %v, got: %v", expErrs, perr.Errors)
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	u, err := ParseUUID("6ba7b8109dad11d180b400c04fd430c8")
+	if err != nil {
+		t.Fatalf("exp: no err, got: %v", err)
+	}
+	exp := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	if u.String() != exp {
+		t.Fatalf("exp: %v, got: %v", exp, u.String())
+	}
+	v, _ := u.Value()
+	if v != exp {
+		t.Fatalf("exp: %v, got: %v", exp, v)
+	}
+
+	for _, s := range []string{"", "6ba7b810-9dad-11d1-80b4-00c04fd430c", "6ba7b810x9dad-11d1-80b4-00c04fd430c8", "zba7b810-9dad-11d1-80b4-00c04fd430c8"} {
+		if _, err := ParseUUID(s); err == nil {
+			t.Fatalf("exp: err for %q, got: nil", s)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package djolar
+
+import "strings"
+
+// Atom values can be quoted with `"` to contain the separators of the
+// query, and `\` escapes the next character, eg.,
+//
+// 	q=n__eq__"Acme, Inc. | Ltd"|t__in__["a,b",c\,d]
+// 	=> name = ? AND tag IN (?)
+// 	=> ["Acme, Inc. | Ltd", ["a,b", "c,d"]]
+
+// LikeEscape escape character of the LIKE operators
+const LikeEscape = '!'
+
+// NullLiteral value standing for SQL NULL, eg., d__eq__$null, quote it to
+// search for the text itself
+const NullLiteral = "$null"
+
+// validQuoting check that every quote of the value is closed, and that
+// the value does not end with a single `\`
+func validQuoting(value string) bool {
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if i++; i == len(value) {
+				return false
+			}
+		case '"':
+			quoted = !quoted
+		}
+	}
+	return !quoted
+}
+
+// unquoteValue remove the quotes and escapes of a value
+func unquoteValue(value string) string {
+	if strings.IndexByte(value, '"') < 0 && strings.IndexByte(value, '\\') < 0 {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			if i+1 < len(value) {
+				i++
+				b.WriteByte(value[i])
+			}
+		case '"':
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// SplitList split a list value on the commas which are neither quoted nor
+// escaped, and unquote the items. Surrounding brackets are optional,
+// eg., ["a,b",c] => [a,b c]
+func SplitList(value string) []string {
+	items := splitListRaw(value)
+	for i, item := range items {
+		items[i] = unquoteValue(item)
+	}
+	return items
+}
+
+// splitListRaw split a list value, keeping the items as written
+func splitListRaw(value string) []string {
+	if strings.HasPrefix(value, "[") {
+		value = value[1:]
+	}
+	if strings.HasSuffix(value, "]") && !strings.HasSuffix(value, `\]`) {
+		value = value[:len(value)-1]
+	}
+
+	items := make([]string, 0, strings.Count(value, ",")+1)
+	quoted := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				items = append(items, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(items, value[start:])
+}
+
+// stripNull remove the null literals from a value, or from the items of a
+// list. It returns the rest of the value, and whether a null literal was
+// found and nothing else is left.
+func stripNull(value string, list bool) (rest string, null, only bool) {
+	if !list {
+		if value == NullLiteral {
+			return "", true, true
+		}
+		return value, false, false
+	}
+	items := splitListRaw(value)
+	kept := make([]string, 0, len(items))
+	for _, item := range items {
+		if item != NullLiteral {
+			kept = append(kept, item)
+		}
+	}
+	if len(kept) == len(items) {
+		return value, false, false
+	}
+	return "[" + strings.Join(kept, ",") + "]", true, len(kept) == 0
+}
+
+// EscapeLike escape the wildcards of a LIKE pattern with LikeEscape, to be
+// used with `ESCAPE '!'`
+func EscapeLike(value string) string {
+	if strings.IndexAny(value, "%_[!") < 0 {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '%', '_', '[', LikeEscape:
+			b.WriteByte(LikeEscape)
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// escapeValue escape the quotes and `\` of a value, so that it is read
+// as it is
+func escapeValue(value string) string {
+	if strings.IndexByte(value, '"') < 0 && strings.IndexByte(value, '\\') < 0 {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package djolar
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	cases := map[string][]string{
+		"1,2,3":              {"1", "2", "3"},
+		"[1,2,3]":            {"1", "2", "3"},
+		`["a,b",c]`:          {"a,b", "c"},
+		`[a\,b,"c\"d",""]`:   {"a,b", `c"d`, ""},
+		`"x|y",z`:            {"x|y", "z"},
+		`[]`:                 {""},
+		`["[a]","b]"]`:       {"[a]", "b]"},
+		`a\\,b`:              {`a\`, "b"},
+		`"Acme, Inc.",Other`: {"Acme, Inc.", "Other"},
+	}
+	for value, exp := range cases {
+		if got := SplitList(value); !reflect.DeepEqual(got, exp) {
+			t.Fatalf("%s: exp: %q, got: %q", value, exp, got)
+		}
+	}
+}
+
+func TestUnquoteValue(t *testing.T) {
+	cases := map[string]string{
+		"abc":           "abc",
+		`"a|b"`:         "a|b",
+		`a\|b`:          "a|b",
+		`"say \"hi\""`:  `say "hi"`,
+		`""`:            "",
+		`"a__b"`:        "a__b",
+		`C:\\Users\\me`: `C:\Users\me`,
+	}
+	for value, exp := range cases {
+		if !validQuoting(value) {
+			t.Fatalf("%s: exp: valid, got: invalid", value)
+		}
+		if got := unquoteValue(value); got != exp {
+			t.Fatalf("exp: %v, got: %v", exp, got)
+		}
+	}
+	for _, value := range []string{`"abc`, `abc\`, `"a\"`} {
+		if validQuoting(value) {
+			t.Fatalf("%s: exp: invalid, got: valid", value)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"abc":    "abc",
+		"50%":    "50!%",
+		"a_b":    "a!_b",
+		"wow!":   "wow!!",
+		"[a-z]":  "![a-z]",
+		"100%_!": "100!%!_!!",
+	}
+	for value, exp := range cases {
+		if got := EscapeLike(value); got != exp {
+			t.Fatalf("exp: %v, got: %v", exp, got)
+		}
+	}
+}
+
+func TestParseQuotedValues(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "t": "tag", "a": "age"}
+
+	res := p.Parse(url.Values{"q": {`n__eq__"Acme, Inc. | Ltd"|t__in__["a,b",c\,d]|(n__ne__"x)"||n__co__50%)|n__eq__a__b`}})
+	exp := "name = ? AND tag IN (?) AND (name <> ? OR name LIKE ? ESCAPE '!') AND name = ?"
+	if res.WhereClause.Where != exp {
+		t.Fatalf("exp: %v, got: %v", exp, res.WhereClause.Where)
+	}
+	args := []interface{}{"Acme, Inc. | Ltd", []string{"a,b", "c,d"}, "x)", "%50!%%", "a__b"}
+	if !reflect.DeepEqual(res.WhereClause.Arguments, args) {
+		t.Fatalf("exp: %v, got: %v", args, res.WhereClause.Arguments)
+	}
+
+	// aggregates still split on the operator
+	res = p.Parse(url.Values{"h": {"a__sum__gt__1"}})
+	if res.HavingClause.Where != "SUM(age) > ?" {
+		t.Fatalf("exp: %v, got: %v", "SUM(age) > ?", res.HavingClause.Where)
+	}
+
+	// unterminated quotes are malformed
+	_, err := p.ParseStrict(url.Values{"q": {`n__eq__"abc`}})
+	if perr, ok := err.(*ParseError); !ok || perr.Errors[0].Reason != ReasonMalformed {
+		t.Fatalf("exp: malformed, got: %v", err)
+	}
+}
+
+func TestParseQuotingChanges(t *testing.T) {
+	p := NewParser()
+	p.Metadata.QueryMapping = map[string]string{"n": "name", "a": "age"}
+
+	// `"` and `\` used to be taken as they are
+	cases := []struct {
+		q     string
+		where string
+		args  []interface{}
+	}{
+		{`n__eq__C:\temp`, "name = ?", []interface{}{"C:temp"}},
+		{`n__eq__C:\\temp`, "name = ?", []interface{}{`C:\temp`}},
+		{`n__eq__5\" screen`, "name = ?", []interface{}{`5" screen`}},
+		{`a__gt__1|n__eq__5" screen|a__lt__9`, "age > ?", []interface{}{"1"}},
+		{"n__eq__$null", "name IS NULL", []interface{}{}},
+		{`n__eq__"$null"`, "name = ?", []interface{}{"$null"}},
+	}
+	for _, c := range cases {
+		res := p.Parse(url.Values{"q": {c.q}})
+		if res.WhereClause.Where != c.where {
+			t.Fatalf("%s exp: %v, got: %v", c.q, c.where, res.WhereClause.Where)
+		}
+		if !reflect.DeepEqual(res.WhereClause.Arguments, c.args) {
+			t.Fatalf("%s exp: %v, got: %v", c.q, c.args, res.WhereClause.Arguments)
+		}
+	}
+
+	// and unbalanced quotes are malformed
+	_, err := p.ParseStrict(url.Values{"q": {`n__eq__5" screen`}})
+	if perr, ok := err.(*ParseError); !ok || perr.Errors[0].Reason != ReasonMalformed {
+		t.Fatalf("exp: malformed, got: %v", err)
+	}
+}